@@ -0,0 +1,402 @@
+package bliss
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+/*
+Analyzer analyzes audio files into Songs.
+
+Analyzer is the extension point used to compose analysis behaviour: DefaultAnalyzer
+calls Analyze, and each Middleware (Cache, Retry, Timeout, Limit, Instrument) wraps
+an Analyzer to add a single concern. Middlewares can be stacked with Chain.
+
+Implementations must be safe for concurrent use.
+*/
+type Analyzer interface {
+	/*
+		Analyze analyzes the audio file at filename, like the package-level Analyze.
+
+		If ctx is done before the analysis completes, Analyze returns ctx.Err().
+	*/
+	Analyze(ctx context.Context, filename string) (*Song, error)
+}
+
+/*
+AnalyzerFunc is an adapter to use an ordinary function as an Analyzer.
+*/
+type AnalyzerFunc func(ctx context.Context, filename string) (*Song, error)
+
+/*
+Analyze calls f(ctx, filename).
+*/
+func (f AnalyzerFunc) Analyze(ctx context.Context, filename string) (*Song, error) {
+	return f(ctx, filename)
+}
+
+/*
+Middleware wraps an Analyzer into another Analyzer adding some behaviour.
+*/
+type Middleware func(next Analyzer) Analyzer
+
+/*
+Chain wraps analyzer with middlewares and returns the resulting Analyzer.
+
+The first middleware is the outermost one: Chain(a, m1, m2) is m1(m2(a)).
+*/
+func Chain(analyzer Analyzer, middlewares ...Middleware) Analyzer {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		analyzer = middlewares[i](analyzer)
+	}
+	return analyzer
+}
+
+/*
+DefaultAnalyzer is the Analyzer backed by the native bliss library, using AnalyzeContext.
+
+It reports errors reading the file (e.g. *os.PathError) before starting the analysis,
+so that they can be told apart from decoding errors, see IsTransient.
+*/
+var DefaultAnalyzer Analyzer = AnalyzerFunc(analyzeDefault)
+
+func analyzeDefault(ctx context.Context, filename string) (*Song, error) {
+	if _, err := os.Stat(filename); err != nil {
+		return nil, err
+	}
+	return AnalyzeContext(ctx, filename)
+}
+
+/*
+detachSong returns a copy of song that does not reference its native memory nor its samples.
+*/
+func detachSong(song *Song) *Song {
+	detached := *song
+	detached.Samples = nil
+	detached.songC = nil
+	return &detached
+}
+
+/*
+Store stores analyzed Songs by key, for use by Cache.
+
+Stored Songs only keep their analysis results and metadata: their Samples are nil,
+and they do not own any native memory.
+
+Implementations must be safe for concurrent use.
+*/
+type Store interface {
+	/*
+		Get returns the Song stored at key, or nil if there is none.
+	*/
+	Get(key string) (*Song, error)
+	/*
+		Put stores song at key, replacing any existing Song.
+	*/
+	Put(key string, song *Song) error
+}
+
+/*
+MemoryStore is an in-memory Store.
+
+The zero value is an empty store ready to use.
+*/
+type MemoryStore struct {
+	mutex sync.RWMutex
+	songs map[string]*Song
+}
+
+/*
+Get returns the Song stored at key, or nil if there is none.
+*/
+func (store *MemoryStore) Get(key string) (*Song, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	song, ok := store.songs[key]
+	if !ok {
+		return nil, nil
+	}
+	return detachSong(song), nil
+}
+
+/*
+Put stores song at key, replacing any existing Song.
+*/
+func (store *MemoryStore) Put(key string, song *Song) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if store.songs == nil {
+		store.songs = make(map[string]*Song)
+	}
+	store.songs[key] = detachSong(song)
+	return nil
+}
+
+func cacheKey(filename string) (string, bool) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return "", false
+	}
+	return filename + "\x00" + info.ModTime().UTC().Format(time.RFC3339Nano) + "\x00" + strconv.FormatInt(info.Size(), 10), true
+}
+
+/*
+Cache returns a Middleware that stores the analysis results in store, and returns
+them on subsequent analyses of the same file.
+
+Entries are keyed by filename, file size and modification time, so that a modified
+file is analyzed again.
+
+Songs returned from the cache have nil Samples and do not own native memory: they
+cannot be passed to functions needing samples, such as EnvelopeSort.
+
+Errors reading from or writing to store are not fatal: the file is analyzed anyway.
+*/
+func Cache(store Store) Middleware {
+	return func(next Analyzer) Analyzer {
+		return AnalyzerFunc(func(ctx context.Context, filename string) (*Song, error) {
+			key, ok := cacheKey(filename)
+			if !ok {
+				return next.Analyze(ctx, filename)
+			}
+			if song, err := store.Get(key); err == nil && song != nil {
+				return song, nil
+			}
+			song, err := next.Analyze(ctx, filename)
+			if err != nil {
+				return nil, err
+			}
+			store.Put(key, song)
+			return song, nil
+		})
+	}
+}
+
+/*
+IsTransient reports whether err is a transient I/O error, such as an interrupted
+system call, a timeout, or an I/O error of a network filesystem, for which retrying
+the analysis may succeed.
+
+Decoding errors of the bliss library are not transient.
+*/
+func IsTransient(err error) bool {
+	if e, ok := err.(*os.PathError); ok {
+		err = e.Err
+	}
+	if e, ok := err.(syscall.Errno); ok {
+		return e.Temporary() || e == syscall.EIO
+	}
+	if e, ok := err.(interface{ Temporary() bool }); ok {
+		return e.Temporary()
+	}
+	return false
+}
+
+/*
+RetryPolicy configures the Retry middleware.
+*/
+type RetryPolicy struct {
+	/*
+		Attempts is the maximum number of analysis attempts. Values lower than 2
+		mean the analysis is never retried.
+	*/
+	Attempts int
+	/*
+		Backoff is the delay before the first retry. It is doubled on each retry.
+	*/
+	Backoff time.Duration
+	/*
+		MaxBackoff caps the delay between two retries. Zero means no cap.
+	*/
+	MaxBackoff time.Duration
+	/*
+		Retryable reports whether an analysis error should be retried.
+		If nil, IsTransient is used.
+	*/
+	Retryable func(err error) bool
+}
+
+/*
+Retry returns a Middleware that retries failed analyses according to policy,
+with an exponential backoff.
+
+Retry stops waiting and returns ctx.Err() when ctx is done.
+*/
+func Retry(policy RetryPolicy) Middleware {
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsTransient
+	}
+	return func(next Analyzer) Analyzer {
+		return AnalyzerFunc(func(ctx context.Context, filename string) (*Song, error) {
+			backoff := policy.Backoff
+			for attempt := 1; ; attempt++ {
+				song, err := next.Analyze(ctx, filename)
+				if err == nil || attempt >= policy.Attempts || !retryable(err) || ctx.Err() != nil {
+					return song, err
+				}
+				timer := time.NewTimer(backoff)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, ctx.Err()
+				}
+				backoff *= 2
+				if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+					backoff = policy.MaxBackoff
+				}
+			}
+		})
+	}
+}
+
+/*
+Timeout returns a Middleware that limits the analysis of each file to timeout.
+
+When the timeout expires, the analysis returns context.DeadlineExceeded.
+*/
+func Timeout(timeout time.Duration) Middleware {
+	return func(next Analyzer) Analyzer {
+		return AnalyzerFunc(func(ctx context.Context, filename string) (*Song, error) {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return next.Analyze(ctx, filename)
+		})
+	}
+}
+
+/*
+Limit returns a Middleware that runs at most n analyses concurrently.
+
+Analyses waiting for their turn return ctx.Err() when ctx is done.
+
+Limit panics if n is not positive.
+*/
+func Limit(n int) Middleware {
+	if n <= 0 {
+		panic("bliss: invalid analysis limit")
+	}
+	return func(next Analyzer) Analyzer {
+		semaphore := make(chan struct{}, n)
+		return AnalyzerFunc(func(ctx context.Context, filename string) (*Song, error) {
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			defer func() { <-semaphore }()
+			return next.Analyze(ctx, filename)
+		})
+	}
+}
+
+/*
+Recorder records the outcome of analyses, for use by Instrument.
+
+Implementations must be safe for concurrent use.
+*/
+type Recorder interface {
+	/*
+		Record is called after each analysis with the analyzed filename,
+		the analysis duration and the analysis error, if any.
+	*/
+	Record(filename string, duration time.Duration, err error)
+}
+
+/*
+Instrument returns a Middleware that records the duration and outcome of every
+analysis to recorder.
+*/
+func Instrument(recorder Recorder) Middleware {
+	return func(next Analyzer) Analyzer {
+		return AnalyzerFunc(func(ctx context.Context, filename string) (*Song, error) {
+			start := time.Now()
+			song, err := next.Analyze(ctx, filename)
+			recorder.Record(filename, time.Since(start), err)
+			return song, err
+		})
+	}
+}
+
+/*
+Metrics is a Recorder that aggregates analysis counts and durations.
+
+The zero value is ready to use.
+*/
+type Metrics struct {
+	mutex    sync.Mutex
+	snapshot MetricsSnapshot
+}
+
+/*
+MetricsSnapshot is a point-in-time copy of the values aggregated by Metrics.
+*/
+type MetricsSnapshot struct {
+	/*
+		Successes is the count of successful analyses.
+	*/
+	Successes int
+	/*
+		Failures is the count of analyses that failed with an error other
+		than a timeout or a cancellation.
+	*/
+	Failures int
+	/*
+		Timeouts is the count of analyses that failed with context.DeadlineExceeded,
+		or with an error that has a Timeout method returning true, such as a
+		*WorkerCrashError of a worker killed after WorkerOptions.Timeout.
+	*/
+	Timeouts int
+	/*
+		Cancellations is the count of analyses that failed with context.Canceled.
+	*/
+	Cancellations int
+	/*
+		Total is the sum of the durations of all analyses.
+	*/
+	Total time.Duration
+	/*
+		Max is the longest duration of an analysis.
+	*/
+	Max time.Duration
+}
+
+/*
+Record implements Recorder.
+*/
+func (metrics *Metrics) Record(filename string, duration time.Duration, err error) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	switch err {
+	case nil:
+		metrics.snapshot.Successes++
+	case context.DeadlineExceeded:
+		metrics.snapshot.Timeouts++
+	case context.Canceled:
+		metrics.snapshot.Cancellations++
+	default:
+		if e, ok := err.(interface{ Timeout() bool }); ok && e.Timeout() {
+			metrics.snapshot.Timeouts++
+		} else {
+			metrics.snapshot.Failures++
+		}
+	}
+	metrics.snapshot.Total += duration
+	if duration > metrics.snapshot.Max {
+		metrics.snapshot.Max = duration
+	}
+}
+
+/*
+Snapshot returns the values aggregated so far.
+*/
+func (metrics *Metrics) Snapshot() MetricsSnapshot {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	return metrics.snapshot
+}
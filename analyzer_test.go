@@ -0,0 +1,139 @@
+package bliss
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	middleware := func(name string) Middleware {
+		return func(next Analyzer) Analyzer {
+			return AnalyzerFunc(func(ctx context.Context, filename string) (*Song, error) {
+				order = append(order, name)
+				return next.Analyze(ctx, filename)
+			})
+		}
+	}
+	analyzer := Chain(AnalyzerFunc(func(ctx context.Context, filename string) (*Song, error) {
+		order = append(order, "analyzer")
+		return &Song{Filename: filename}, nil
+	}), middleware("first"), middleware("second"))
+	if _, err := analyzer.Analyze(context.Background(), "song.flac"); err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "first,second,analyzer", order[0]+","+order[1]+","+order[2], "chain order")
+}
+
+func TestRetry(t *testing.T) {
+	var calls int32
+	analyzer := Chain(AnalyzerFunc(func(ctx context.Context, filename string) (*Song, error) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return nil, &os.PathError{Op: "open", Path: filename, Err: syscall.EIO}
+		}
+		return &Song{Filename: filename}, nil
+	}), Retry(RetryPolicy{Attempts: 5, Backoff: time.Millisecond}))
+	if _, err := analyzer.Analyze(context.Background(), "song.flac"); err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, 3, int(calls), "retry attempts")
+
+	calls = 0
+	analyzer = Chain(AnalyzerFunc(func(ctx context.Context, filename string) (*Song, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errors.New("bliss: couldn't decode song")
+	}), Retry(RetryPolicy{Attempts: 5, Backoff: time.Millisecond}))
+	if _, err := analyzer.Analyze(context.Background(), "song.flac"); err == nil {
+		t.Error("expected decode error")
+	}
+	assertInt(t, 1, int(calls), "non-transient attempts")
+}
+
+func TestTimeout(t *testing.T) {
+	analyzer := Chain(AnalyzerFunc(func(ctx context.Context, filename string) (*Song, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}), Timeout(time.Millisecond))
+	if _, err := analyzer.Analyze(context.Background(), "song.flac"); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded, got: %v", err)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	var running, max int32
+	analyzer := Chain(AnalyzerFunc(func(ctx context.Context, filename string) (*Song, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return &Song{}, nil
+	}), Limit(2))
+	done := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		go func() {
+			analyzer.Analyze(context.Background(), "song.flac")
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		<-done
+	}
+	if max > 2 {
+		t.Errorf("expected at most 2 concurrent analyses, got: %d", max)
+	}
+}
+
+func TestCacheAndMetrics(t *testing.T) {
+	file, err := ioutil.TempFile("", "bliss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	var calls int
+	var metrics Metrics
+	analyzer := Chain(AnalyzerFunc(func(ctx context.Context, filename string) (*Song, error) {
+		calls++
+		return &Song{Filename: filename, Force: 1.5, Samples: make([]int8, 4)}, nil
+	}), Instrument(&metrics), Cache(&MemoryStore{}))
+	for i := 0; i < 3; i++ {
+		song, err := analyzer.Analyze(context.Background(), file.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertFloat(t, 1.5, song.Force, "cached song force")
+	}
+	assertInt(t, 1, calls, "analyses with cache")
+	assertInt(t, 3, metrics.Snapshot().Successes, "recorded successes")
+}
+
+func TestLimitInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Limit(0) to panic")
+		}
+	}()
+	Limit(0)
+}
+
+func TestMetricsTimeouts(t *testing.T) {
+	var metrics Metrics
+	metrics.Record("a.flac", time.Second, context.DeadlineExceeded)
+	metrics.Record("b.flac", time.Second, &WorkerCrashError{Filename: "b.flac", Err: context.DeadlineExceeded})
+	metrics.Record("c.flac", time.Second, &WorkerCrashError{Filename: "c.flac", Err: errors.New("exit status 3")})
+	snapshot := metrics.Snapshot()
+	assertInt(t, 2, snapshot.Timeouts, "recorded timeouts")
+	assertInt(t, 1, snapshot.Failures, "recorded failures")
+}
@@ -0,0 +1,180 @@
+package bliss
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// onsetWindow is the half-width, in envelope points, of the adaptive onset threshold.
+	onsetWindow = 10
+	// onsetMinDistance is the minimum distance, in envelope points, between two onsets.
+	onsetMinDistance = 5
+	// beatsPerBar is the assumed meter when guessing downbeats.
+	beatsPerBar = 4
+)
+
+/*
+BeatGrid stores the beat positions of a Song.
+*/
+type BeatGrid struct {
+	/*
+		BPM is the tempo of the beat grid in beats per minute, as returned by EstimateTempo.
+	*/
+	BPM float64
+	/*
+		Beats are the times of the beats, from the start of the song.
+	*/
+	Beats []time.Duration
+	/*
+		Downbeats are the times of the guessed first beats of each bar, assuming
+		four beats per bar. They are a subset of Beats.
+	*/
+	Downbeats []time.Duration
+}
+
+func envelopeTime(point float64, rate float64) time.Duration {
+	return time.Duration(point / rate * float64(time.Second))
+}
+
+func songOnsets(song *Song) ([]float64, float64, error) {
+	mono, err := song.monoSamples()
+	if err != nil {
+		return nil, 0, err
+	}
+	rate := float64(song.SampleRate) / float64(envelopeHop(song.SampleRate))
+	return onsetStrength(envelopeOf(mono, song.SampleRate)), rate, nil
+}
+
+// pickOnsets returns the indexes of the peaks of the onset strength that stand out
+// from their neighbourhood.
+func pickOnsets(onsets []float64) []int {
+	var max float64
+	for _, v := range onsets {
+		max = math.Max(max, v)
+	}
+	threshold := movingAverage(onsets, 2*onsetWindow+1)
+	var peaks []int
+	for i, v := range onsets {
+		if v <= 1.5*threshold[i]+0.05*max {
+			continue
+		}
+		peak := true
+		for j := i - onsetMinDistance/2; j <= i+onsetMinDistance/2 && peak; j++ {
+			if j >= 0 && j < len(onsets) && j != i && (onsets[j] > v || onsets[j] == v && j < i) {
+				peak = false
+			}
+		}
+		if !peak {
+			continue
+		}
+		if len(peaks) > 0 && i-peaks[len(peaks)-1] < onsetMinDistance {
+			continue
+		}
+		peaks = append(peaks, i)
+	}
+	return peaks
+}
+
+/*
+DetectOnsets returns the times of the onsets (note or percussion attacks) of a Song,
+from the start of the song.
+
+Onsets are the peaks of the positive derivative of the envelope returned by
+AnalyzeEnvelope.
+
+If the Song has no samples, DetectOnsets returns ErrNoSamples.
+*/
+func DetectOnsets(song *Song) ([]time.Duration, error) {
+	onsets, rate, err := songOnsets(song)
+	if err != nil {
+		return nil, err
+	}
+	peaks := pickOnsets(onsets)
+	times := make([]time.Duration, len(peaks))
+	for i, peak := range peaks {
+		times[i] = envelopeTime(float64(peak), rate)
+	}
+	return times, nil
+}
+
+/*
+DetectBeats returns the beat grid of a Song: the times of its beats and guessed downbeats.
+
+The beat period is the tempo returned by EstimateTempo. The grid is aligned on the
+song onsets, each beat being adjusted to the strongest onset close to its expected time.
+If the Song has no tempo, e.g. if it is silent, the grid has no beats.
+
+If the Song has no samples, DetectBeats returns ErrNoSamples.
+*/
+func DetectBeats(song *Song) (*BeatGrid, error) {
+	onsets, rate, err := songOnsets(song)
+	if err != nil {
+		return nil, err
+	}
+	tempo := estimateTempo(onsets, rate)
+	grid := &BeatGrid{
+		BPM: tempo.BPM,
+	}
+	if tempo.BPM == 0 {
+		return grid, nil
+	}
+	beats := beatPositions(onsets, 60*rate/tempo.BPM)
+	for _, beat := range beats {
+		grid.Beats = append(grid.Beats, envelopeTime(float64(beat), rate))
+	}
+	for i := downbeatOffset(onsets, beats); i < len(beats); i += beatsPerBar {
+		grid.Downbeats = append(grid.Downbeats, grid.Beats[i])
+	}
+	return grid, nil
+}
+
+func beatPositions(onsets []float64, period float64) []int {
+	// the phase maximizing the onset strength on a regular grid
+	var phase int
+	best := -1.0
+	for p := 0; p < int(period) && p < len(onsets); p++ {
+		var sum float64
+		for position := float64(p); int(position) < len(onsets); position += period {
+			sum += onsets[int(position)]
+		}
+		if sum > best {
+			best = sum
+			phase = p
+		}
+	}
+
+	tolerance := int(0.1 * period)
+	var beats []int
+	for expected := float64(phase); int(expected) < len(onsets); {
+		beat := int(expected)
+		for i := beat - tolerance; i <= beat+tolerance; i++ {
+			if i >= 0 && i < len(onsets) && onsets[i] > onsets[beat] {
+				beat = i
+			}
+		}
+		if len(beats) == 0 || beat > beats[len(beats)-1] {
+			beats = append(beats, beat)
+		}
+		expected = float64(beat) + period
+	}
+	return beats
+}
+
+// downbeatOffset returns the index of the first downbeat in beats, the one whose bar
+// positions have the strongest onsets.
+func downbeatOffset(onsets []float64, beats []int) int {
+	var offset int
+	best := -1.0
+	for o := 0; o < beatsPerBar && o < len(beats); o++ {
+		var sum float64
+		for i := o; i < len(beats); i += beatsPerBar {
+			sum += onsets[beats[i]]
+		}
+		if sum > best {
+			best = sum
+			offset = o
+		}
+	}
+	return offset
+}
@@ -0,0 +1,51 @@
+package bliss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectOnsetsAndBeats(t *testing.T) {
+	const rate = 22050
+	samples := clicks(rate, 10*rate, 120)
+	// accent every fourth click, starting with the second one
+	for i := range samples {
+		if (i/(rate/2))%4 != 1 {
+			samples[i] *= 0.5
+		}
+	}
+	song := newTestSong(rate, 1, samples)
+
+	onsets, err := DetectOnsets(song)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, 20, len(onsets), "onsets count")
+	for i, onset := range onsets {
+		if d := onset - time.Duration(i)*time.Second/2; d < -30*time.Millisecond || d > 30*time.Millisecond {
+			t.Errorf("onset %d: expected at %v, got: %v", i, time.Duration(i)*time.Second/2, onset)
+		}
+	}
+
+	grid, err := DetectBeats(song)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(grid.Beats) < 20 || len(grid.Beats) > 21 {
+		t.Errorf("expected 20 or 21 beats, got: %d", len(grid.Beats))
+	}
+	if len(grid.Downbeats) == 0 || grid.Downbeats[0] < 450*time.Millisecond || grid.Downbeats[0] > 550*time.Millisecond {
+		t.Errorf("expected the first downbeat at 500ms, got: %v", grid.Downbeats)
+	}
+}
+
+func TestDetectBeatsSilence(t *testing.T) {
+	const rate = 22050
+	grid, err := DetectBeats(newTestSong(rate, 1, make([]float64, 5*rate)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grid.BPM != 0 || len(grid.Beats) != 0 || len(grid.Downbeats) != 0 {
+		t.Errorf("expected no beats in silence, got: %+v", grid)
+	}
+}
@@ -10,6 +10,15 @@ package bliss
 static inline const char * bl_version_str() {
 	return xstr(BL_VERSION);
 }
+
+static inline void bl_set_samples(struct bl_song *song, void *samples, int nSamples, int channels, int sample_rate, int nb_bytes_per_sample) {
+	song->sample_array = samples;
+	song->nSamples = nSamples;
+	song->channels = channels;
+	song->sample_rate = sample_rate;
+	song->nb_bytes_per_sample = nb_bytes_per_sample;
+	song->duration = nSamples / channels / sample_rate;
+}
 */
 import "C"
 import (
@@ -47,7 +56,8 @@ ForceVector is a vector representing ratings of a song (tempo, attack, amplitude
 */
 type ForceVector struct {
 	/*
-		The tempo rating is the beats per minute of a song.
+		The tempo rating is derived from the dominant beats of a song. It is a
+		rating rather than beats per minute: see EstimateTempo for an actual tempo.
 	*/
 	Tempo float32
 	/*
@@ -90,7 +100,8 @@ Envelope stores envelope-related characteristics of a Song.
 */
 type Envelope struct {
 	/*
-		The tempo rating is the beats per minute of a song.
+		The tempo rating is derived from the dominant beats of a song. It is a
+		rating rather than beats per minute: see EstimateTempo for an actual tempo.
 	*/
 	Tempo float32
 	/*
@@ -220,9 +231,10 @@ type Song struct {
 /*
 Close frees any native resources owned by this Song.
 
-Calling Close more than once is a no-op.
+Calling Close more than once is a no-op, and a closed Song is not freed again by the GC.
 
-The Song must not be used after it is closed.
+The Song must not be used after it is closed: its Samples are nil, and its analysis
+results and metadata are kept.
 */
 func (song *Song) Close() {
 	if song.songC == nil {
@@ -230,6 +242,9 @@ func (song *Song) Close() {
 	}
 	C.bl_free_song(song.songC)
 	C.free(unsafe.Pointer(song.songC))
+	song.songC = nil
+	song.Samples = nil
+	runtime.SetFinalizer(song, nil)
 }
 
 func closeSong(song *Song) {
@@ -269,6 +284,21 @@ func newSong(songC *C.struct_bl_song) *Song {
 	return song
 }
 
+// sampleBytes returns the raw interleaved samples of the song.
+// bliss counts nSamples in samples rather than bytes, so native songs
+// hold nSamples*BytesPerSample bytes.
+func (song *Song) sampleBytes() []int8 {
+	if song.songC == nil {
+		return song.Samples
+	}
+	n := int(song.songC.nSamples) * int(song.songC.nb_bytes_per_sample)
+	return *(*[]int8)(unsafe.Pointer(&reflect.SliceHeader{
+		Data: uintptr(unsafe.Pointer(song.songC.sample_array)),
+		Len:  n,
+		Cap:  n,
+	}))
+}
+
 /*
 Decode decodes an audio file, without analyzing it and returns it as a Song.
 
@@ -460,6 +490,24 @@ func FrequencySort(song *Song) float32 {
 	return r
 }
 
+// rateSamples computes the ratings of raw, interleaved samples in the format of song.
+func rateSamples(song *Song, raw []int8) ForceVector {
+	songC := (*C.struct_bl_song)(C.calloc(1, C.sizeof_struct_bl_song))
+	defer C.free(unsafe.Pointer(songC))
+	samples := C.CBytes(*(*[]byte)(unsafe.Pointer(&raw)))
+	defer C.free(samples)
+	C.bl_set_samples(songC, samples, C.int(len(raw)/song.BytesPerSample), C.int(song.Channels), C.int(song.SampleRate), C.int(song.BytesPerSample))
+
+	var envelopeC C.struct_envelope_result_s
+	C.bl_envelope_sort(songC, &envelopeC)
+	return ForceVector{
+		Tempo:     float32(envelopeC.tempo),
+		Attack:    float32(envelopeC.attack),
+		Amplitude: float32(C.bl_amplitude_sort(songC)),
+		Frequency: float32(C.bl_frequency_sort(songC)),
+	}
+}
+
 /*
 Version returns the runtime version of the C bliss library as a string, e.g: "1.1".
 */
@@ -2,6 +2,8 @@ package bliss
 
 import (
 	"fmt"
+	"math"
+	"runtime"
 	"testing"
 )
 
@@ -15,6 +17,12 @@ func assertFloat(t *testing.T, expected float32, actual float32, message string)
 	}
 }
 
+func assertNear(t *testing.T, expected float64, actual float64, tolerance float64, message string) {
+	if math.Abs(expected-actual) > tolerance {
+		t.Errorf("%s: value mismatch: expected: [%f], got: [%f]", message, expected, actual)
+	}
+}
+
 func assertInt(t *testing.T, expected int, actual int, message string) {
 	if expected != actual {
 		t.Error(fmt.Sprintf("%s: float mismatch: expected: [%d], got: [%d]", message, expected, actual))
@@ -27,6 +35,21 @@ func assertString(t *testing.T, expected string, actual string, message string)
 	}
 }
 
+func TestCloseTwice(t *testing.T) {
+	song, err := Analyze("audio/song.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	song.Close()
+	song.Close()
+	if song.Samples != nil {
+		t.Error("expected the samples of a closed song to be released")
+	}
+	// the finalizer must not free the song again
+	runtime.GC()
+	runtime.GC()
+}
+
 func TestAnalyze(t *testing.T) {
 	song, err := Analyze("audio/song.flac")
 	if err != nil {
@@ -55,3 +78,30 @@ func TestAnalyze(t *testing.T) {
 	assertString(t, "02", song.TrackNumber, "song track number")
 	assertString(t, "Pop", song.Genre, "song genre")
 }
+
+// newTestSong returns a 16-bit Song, without native memory, from samples in [-1, 1]
+// interleaved per channel.
+func newTestSong(sampleRate int, channels int, samples []float64) *Song {
+	raw := make([]int8, 2*len(samples))
+	for i, v := range samples {
+		s := int16(v * 32767)
+		raw[2*i] = int8(uint8(s))
+		raw[2*i+1] = int8(s >> 8)
+	}
+	return &Song{
+		Samples:        raw,
+		Channels:       channels,
+		SampleRate:     sampleRate,
+		BytesPerSample: 2,
+		Duration:       uint64(len(samples) / channels / sampleRate),
+	}
+}
+
+// sine returns n samples of a sine wave of the given frequency and amplitude.
+func sine(sampleRate int, n int, frequency float64, amplitude float64) []float64 {
+	samples := make([]float64, n)
+	for i := range samples {
+		samples[i] = amplitude * math.Sin(2*math.Pi*frequency*float64(i)/float64(sampleRate))
+	}
+	return samples
+}
@@ -0,0 +1,233 @@
+package bliss
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// calibrationQuantiles is the number of quantiles stored by quantile calibrations, minus one.
+	calibrationQuantiles = 100
+	// mixtureIterations is the maximum number of EM iterations of mixture calibrations.
+	mixtureIterations = 200
+)
+
+/*
+CalibrationMethod is the method used to fit a Calibration.
+*/
+type CalibrationMethod string
+
+const (
+	/*
+		QuantileCalibration calibrates forces by their rank in the library: half of the
+		library is Calm, and half is Loud.
+	*/
+	QuantileCalibration CalibrationMethod = "quantile"
+	/*
+		MixtureCalibration fits a mixture of two gaussians (a calm and a loud class) to the
+		forces of the library, and calibrates forces by the probability that they belong to
+		the loud class. Unlike QuantileCalibration, the classes can have different sizes.
+	*/
+	MixtureCalibration CalibrationMethod = "mixture"
+)
+
+/*
+Calibration rates the Force of songs relatively to a library, rather than relatively to 0
+as ForceRating does, e.g. for libraries where nearly all songs are Calm.
+
+A Calibration can be serialized, e.g. with encoding/json, to rate songs analyzed later
+with the same calibration.
+*/
+type Calibration struct {
+	/*
+		Method is the method used to fit the calibration.
+	*/
+	Method CalibrationMethod
+	/*
+		Quantiles stores the forces of the library at each percentile, from 0 to 100,
+		for QuantileCalibration.
+	*/
+	Quantiles []float64
+	/*
+		Means stores the means of the calm and loud classes, for MixtureCalibration.
+	*/
+	Means [2]float64
+	/*
+		Deviation is the standard deviation of both classes, for MixtureCalibration.
+	*/
+	Deviation float64
+	/*
+		Weights stores the shares of the calm and loud classes in the library, for MixtureCalibration.
+	*/
+	Weights [2]float64
+}
+
+/*
+FitCalibration fits a Calibration to the forces of the songs of a library (their Force).
+
+FitCalibration panics if there are no forces, or if the method is unknown.
+*/
+func FitCalibration(forces []float32, method CalibrationMethod) *Calibration {
+	if len(forces) == 0 {
+		panic("bliss: no forces to fit")
+	}
+	sorted := make([]float64, len(forces))
+	for i, force := range forces {
+		sorted[i] = float64(force)
+	}
+	sort.Float64s(sorted)
+
+	calibration := &Calibration{
+		Method: method,
+	}
+	switch method {
+	case QuantileCalibration:
+		calibration.Quantiles = make([]float64, calibrationQuantiles+1)
+		for i := range calibration.Quantiles {
+			calibration.Quantiles[i] = quantile(sorted, float64(i)/calibrationQuantiles)
+		}
+	case MixtureCalibration:
+		calibration.fitMixture(sorted)
+	default:
+		panic("bliss: unknown calibration method")
+	}
+	return calibration
+}
+
+// fitMixture fits a mixture of two gaussians with a shared variance with the EM algorithm.
+func (calibration *Calibration) fitMixture(forces []float64) {
+	var mean, variance float64
+	for _, force := range forces {
+		mean += force
+	}
+	mean /= float64(len(forces))
+	for _, force := range forces {
+		variance += (force - mean) * (force - mean)
+	}
+	variance /= float64(len(forces))
+	// avoid degenerate classes, e.g. when all forces are equal
+	minVariance := 1e-3*variance + 1e-12
+
+	calibration.Means = [2]float64{quantile(forces, 0.25), quantile(forces, 0.75)}
+	calibration.Deviation = math.Sqrt(variance + minVariance)
+	calibration.Weights = [2]float64{0.5, 0.5}
+	responsibilities := make([]float64, len(forces))
+	likelihood := math.Inf(-1)
+	for iteration := 0; iteration < mixtureIterations; iteration++ {
+		previous := likelihood
+		likelihood = 0
+		for i, force := range forces {
+			calm, loud := calibration.densities(force)
+			likelihood += math.Log(calm + loud + 1e-300)
+			responsibilities[i] = 0.5
+			if calm+loud > 0 {
+				responsibilities[i] = loud / (calm + loud)
+			}
+		}
+		var weight, sums [2]float64
+		for i, force := range forces {
+			r := responsibilities[i]
+			weight[0] += 1 - r
+			weight[1] += r
+			sums[0] += (1 - r) * force
+			sums[1] += r * force
+		}
+		var squares float64
+		for c := range calibration.Means {
+			if weight[c] > 0 {
+				calibration.Means[c] = sums[c] / weight[c]
+			}
+			calibration.Weights[c] = weight[c] / float64(len(forces))
+		}
+		for i, force := range forces {
+			r := responsibilities[i]
+			squares += (1-r)*(force-calibration.Means[0])*(force-calibration.Means[0]) + r*(force-calibration.Means[1])*(force-calibration.Means[1])
+		}
+		calibration.Deviation = math.Sqrt(squares/float64(len(forces)) + minVariance)
+		if likelihood-previous < 1e-9*math.Abs(likelihood) {
+			break
+		}
+	}
+	if calibration.Means[0] > calibration.Means[1] {
+		calibration.Means[0], calibration.Means[1] = calibration.Means[1], calibration.Means[0]
+		calibration.Weights[0], calibration.Weights[1] = calibration.Weights[1], calibration.Weights[0]
+	}
+}
+
+// densities returns the weighted densities of the calm and loud classes at force.
+func (calibration *Calibration) densities(force float64) (float64, float64) {
+	var densities [2]float64
+	for c, mean := range calibration.Means {
+		z := (force - mean) / calibration.Deviation
+		densities[c] = calibration.Weights[c] * math.Exp(-z*z/2) / (calibration.Deviation * math.Sqrt(2*math.Pi))
+	}
+	return densities[0], densities[1]
+}
+
+/*
+Score returns the calibrated force, between 0 (calmest) and 1 (loudest): the share of
+the library calmer than force for QuantileCalibration, or the probability that the song
+belongs to the loud class for MixtureCalibration.
+*/
+func (calibration *Calibration) Score(force float32) float64 {
+	f := float64(force)
+	switch calibration.Method {
+	case QuantileCalibration:
+		quantiles := calibration.Quantiles
+		if len(quantiles) == 0 {
+			return 0.5
+		}
+		i := sort.SearchFloat64s(quantiles, f)
+		switch i {
+		case 0:
+			return 0
+		case len(quantiles):
+			return 1
+		}
+		// quantiles[i-1] < f <= quantiles[i]
+		position := float64(i-1) + (f-quantiles[i-1])/(quantiles[i]-quantiles[i-1])
+		return position / float64(len(quantiles)-1)
+	case MixtureCalibration:
+		if calibration.Deviation <= 0 {
+			return 0.5
+		}
+		// with a shared variance, the log-odds of the classes are linear in force
+		d := calibration.Deviation * calibration.Deviation
+		logOdds := (calibration.Means[1]-calibration.Means[0])*(f-(calibration.Means[0]+calibration.Means[1])/2)/d +
+			math.Log(calibration.Weights[1]/calibration.Weights[0])
+		return 1 / (1 + math.Exp(-logOdds))
+	default:
+		return 0.5
+	}
+}
+
+/*
+Rating returns the calibrated ForceRating of force: Calm if its Score is lower than 0.5,
+Loud if it is higher, and Unknown otherwise.
+*/
+func (calibration *Calibration) Rating(force float32) ForceRating {
+	score := calibration.Score(force)
+	switch {
+	case score > 0.5:
+		return Loud
+	case score < 0.5:
+		return Calm
+	default:
+		return Unknown
+	}
+}
+
+/*
+Level returns the calibrated force of force on a scale of levels levels, from 0 (calmest)
+to levels-1 (loudest), by splitting its Score evenly, e.g. with 5 levels, for a 5-star rating.
+*/
+func (calibration *Calibration) Level(force float32, levels int) int {
+	level := int(calibration.Score(force) * float64(levels))
+	if level >= levels {
+		level = levels - 1
+	}
+	if level < 0 {
+		level = 0
+	}
+	return level
+}
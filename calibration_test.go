@@ -0,0 +1,72 @@
+package bliss
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestQuantileCalibration(t *testing.T) {
+	var forces []float32
+	for i := 0; i <= 100; i++ {
+		// a library of calm songs
+		forces = append(forces, float32(-30+i/10))
+	}
+	calibration := FitCalibration(forces, QuantileCalibration)
+	if r := calibration.Rating(-28); r != Calm {
+		t.Errorf("expected a calm rating, got: %v", r)
+	}
+	if r := calibration.Rating(-21); r != Loud {
+		t.Errorf("expected a loud rating, got: %v", r)
+	}
+	assertNear(t, 0, calibration.Score(-40), 1e-9, "lowest score")
+	assertNear(t, 1, calibration.Score(0), 1e-9, "highest score")
+	assertInt(t, 0, calibration.Level(-30, 5), "lowest level")
+	assertInt(t, 4, calibration.Level(-20, 5), "highest level")
+	assertInt(t, 2, calibration.Level(-25, 5), "middle level")
+
+	encoded, err := json.Marshal(calibration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Calibration
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(calibration, &decoded) {
+		t.Errorf("unexpected decoded calibration: %+v", decoded)
+	}
+}
+
+func TestMixtureCalibration(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	var forces []float32
+	// 80% of calm songs around -30, 20% of louder songs around -15
+	for i := 0; i < 800; i++ {
+		forces = append(forces, float32(-30+2*random.NormFloat64()))
+	}
+	for i := 0; i < 200; i++ {
+		forces = append(forces, float32(-15+2*random.NormFloat64()))
+	}
+	calibration := FitCalibration(forces, MixtureCalibration)
+	assertNear(t, -30, calibration.Means[0], 0.5, "calm mean")
+	assertNear(t, -15, calibration.Means[1], 0.5, "loud mean")
+	assertNear(t, 2, calibration.Deviation, 0.2, "deviation")
+	assertNear(t, 0.8, calibration.Weights[0], 0.02, "calm weight")
+
+	if r := calibration.Rating(-25); r != Calm {
+		t.Errorf("expected a calm rating, got: %v", r)
+	}
+	if r := calibration.Rating(-18); r != Loud {
+		t.Errorf("expected a loud rating, got: %v", r)
+	}
+	// the classes are unbalanced: most songs are rated calm
+	calm := 0
+	for _, force := range forces {
+		if calibration.Rating(force) == Calm {
+			calm++
+		}
+	}
+	assertNear(t, 800, float64(calm), 5, "calm songs count")
+}
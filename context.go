@@ -0,0 +1,74 @@
+package bliss
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+var abandoned int64
+
+/*
+AbandonedCalls returns the number of native bliss calls abandoned by DecodeContext
+or AnalyzeContext that are still running in the background.
+
+A steadily growing value means that files are hanging the native library: such
+files are better analyzed with a WorkerPool, which kills hung analyses.
+*/
+func AbandonedCalls() int {
+	return int(atomic.LoadInt64(&abandoned))
+}
+
+func runContext(ctx context.Context, call func() (*Song, error)) (*Song, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	type result struct {
+		song *Song
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		song, err := call()
+		done <- result{song, err}
+	}()
+	select {
+	case r := <-done:
+		return r.song, r.err
+	case <-ctx.Done():
+		atomic.AddInt64(&abandoned, 1)
+		go func() {
+			if r := <-done; r.song != nil {
+				// Close clears the finalizer, so the song is not freed again by the GC
+				r.song.Close()
+			}
+			atomic.AddInt64(&abandoned, -1)
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+/*
+DecodeContext is like Decode, but returns ctx.Err() as soon as ctx is done.
+
+The native decoding cannot be interrupted: when ctx is done first, it keeps running
+in the background until it completes, and its Song is then closed. Such calls are
+counted by AbandonedCalls.
+*/
+func DecodeContext(ctx context.Context, filename string) (*Song, error) {
+	return runContext(ctx, func() (*Song, error) {
+		return Decode(filename)
+	})
+}
+
+/*
+AnalyzeContext is like Analyze, but returns ctx.Err() as soon as ctx is done.
+
+The native analysis cannot be interrupted: when ctx is done first, it keeps running
+in the background until it completes, and its Song is then closed. Such calls are
+counted by AbandonedCalls. To actually stop hung analyses, use a WorkerPool.
+*/
+func AnalyzeContext(ctx context.Context, filename string) (*Song, error) {
+	return runContext(ctx, func() (*Song, error) {
+		return Analyze(filename)
+	})
+}
@@ -0,0 +1,51 @@
+package bliss
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRunContext(t *testing.T) {
+	release := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err := runContext(ctx, func() (*Song, error) {
+		<-release
+		return &Song{}, nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded, got: %v", err)
+	}
+	assertInt(t, 1, AbandonedCalls(), "abandoned calls while hung")
+
+	close(release)
+	for i := 0; i < 100 && AbandonedCalls() > 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assertInt(t, 0, AbandonedCalls(), "abandoned calls after completion")
+}
+
+func TestRunContextCloseAbandoned(t *testing.T) {
+	release := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(time.Millisecond)
+		cancel()
+	}()
+	// the abandoned song is closed in the background, then collected
+	_, err := runContext(ctx, func() (*Song, error) {
+		<-release
+		return Analyze("audio/song.flac")
+	})
+	if err != context.Canceled {
+		t.Errorf("expected canceled, got: %v", err)
+	}
+	close(release)
+	for i := 0; i < 1000 && AbandonedCalls() > 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	runtime.GC()
+	runtime.GC()
+}
@@ -0,0 +1,215 @@
+package bliss
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	defaultCrossfadeLength = 8 * time.Second
+	defaultCrossfadeSearch = 30 * time.Second
+	// crossfadeFrame is the resolution of the energy curves compared for crossfades.
+	crossfadeFrame = 100 * time.Millisecond
+	// crossfadeEnergyRange is the energy difference, in dB, giving an energy match of zero.
+	crossfadeEnergyRange = 20
+	// crossfadeDistanceScale is the distance between force vectors giving a similarity of 0.5.
+	crossfadeDistanceScale = 10
+)
+
+/*
+CrossfadeOptions configures SuggestCrossfade.
+*/
+type CrossfadeOptions struct {
+	/*
+		Length is the duration of the crossfade. Zero means 8 seconds.
+	*/
+	Length time.Duration
+	/*
+		Search is how far from the end of the first song, and from the start of the
+		second song, mix points are searched. Zero means 30 seconds.
+	*/
+	Search time.Duration
+	/*
+		Silence configures the detection of the leading and trailing silences,
+		which are skipped.
+	*/
+	Silence SilenceOptions
+}
+
+/*
+Crossfade is a suggested transition from a song A to a song B.
+*/
+type Crossfade struct {
+	/*
+		MixOut is the time in song A at which to start fading A out and B in.
+	*/
+	MixOut time.Duration
+	/*
+		MixIn is the time in song B at which B starts playing.
+	*/
+	MixIn time.Duration
+	/*
+		Length is the duration of the crossfade.
+	*/
+	Length time.Duration
+	/*
+		Distance is the distance between the force vectors of the faded parts of A and B.
+	*/
+	Distance float32
+	/*
+		EnergyMatch is how close the energies of the faded parts of A and B are,
+		between 0 (20 dB apart or more) and 1 (same energy).
+	*/
+	EnergyMatch float64
+	/*
+		Quality is the transition quality, between 0 and 1, combining EnergyMatch and
+		the similarity of the faded parts of A and B derived from Distance.
+	*/
+	Quality float64
+}
+
+type crossfadeSide struct {
+	audible Interval
+	// energy is the level in dB of each crossfadeFrame
+	energy []float64
+	beats  []time.Duration
+}
+
+func newCrossfadeSide(song *Song, options SilenceOptions) (*crossfadeSide, error) {
+	silence, err := DetectSilence(song, options)
+	if err != nil {
+		return nil, err
+	}
+	channels, err := song.channelSamples()
+	if err != nil {
+		return nil, err
+	}
+	grid, err := DetectBeats(song)
+	if err != nil {
+		return nil, err
+	}
+	frameLength := int(crossfadeFrame.Seconds() * float64(song.SampleRate))
+	if frameLength == 0 {
+		frameLength = 1
+	}
+	powers := framePowers(channels, frameLength)
+	energy := make([]float64, len(powers))
+	for i, power := range powers {
+		energy[i] = decibels(power)
+	}
+	return &crossfadeSide{
+		audible: silence.Audible,
+		energy:  energy,
+		beats:   grid.Beats,
+	}, nil
+}
+
+// candidates returns the mix points between from and to: the beats if there are any,
+// or every second otherwise.
+func (side *crossfadeSide) candidates(from time.Duration, to time.Duration) []time.Duration {
+	var candidates []time.Duration
+	for _, beat := range side.beats {
+		if beat >= from && beat <= to {
+			candidates = append(candidates, beat)
+		}
+	}
+	if len(candidates) == 0 {
+		for t := from; t <= to; t += time.Second {
+			candidates = append(candidates, t)
+		}
+	}
+	return candidates
+}
+
+func (side *crossfadeSide) meanEnergy(start time.Duration, length time.Duration) float64 {
+	from, to := int(start/crossfadeFrame), int((start+length)/crossfadeFrame)
+	if to > len(side.energy) {
+		to = len(side.energy)
+	}
+	if from >= to {
+		return decibels(0)
+	}
+	var sum float64
+	for _, e := range side.energy[from:to] {
+		sum += e
+	}
+	return sum / float64(to-from)
+}
+
+/*
+SuggestCrossfade suggests where to crossfade from song a to song b.
+
+Mix points are searched at the beats (see DetectBeats) of the end of a and of the start
+of b, skipping their silences (see DetectSilence). The suggested points are the ones
+whose faded parts have the closest energies, preferring to play as much of both songs
+as possible.
+
+If a Song has no samples, SuggestCrossfade returns ErrNoSamples.
+*/
+func SuggestCrossfade(a *Song, b *Song, options CrossfadeOptions) (*Crossfade, error) {
+	length := options.Length
+	if length == 0 {
+		length = defaultCrossfadeLength
+	}
+	search := options.Search
+	if search == 0 {
+		search = defaultCrossfadeSearch
+	}
+	out, err := newCrossfadeSide(a, options.Silence)
+	if err != nil {
+		return nil, err
+	}
+	in, err := newCrossfadeSide(b, options.Silence)
+	if err != nil {
+		return nil, err
+	}
+	for _, audible := range []Interval{out.audible, in.audible} {
+		if d := audible.End - audible.Start; d < length {
+			length = d
+		}
+	}
+	if search < length {
+		search = length
+	}
+
+	outFrom, outTo := out.audible.End-search, out.audible.End-length
+	if outFrom < out.audible.Start {
+		outFrom = out.audible.Start
+	}
+	inFrom, inTo := in.audible.Start, in.audible.Start+search-length
+	if inTo > in.audible.End-length {
+		inTo = in.audible.End - length
+	}
+
+	crossfade := &Crossfade{
+		Length:      length,
+		EnergyMatch: -1,
+	}
+	outCandidates := out.candidates(outFrom, outTo)
+	inCandidates := in.candidates(inFrom, inTo)
+	// latest mix out and earliest mix in first, so that they win ties
+	for i := len(outCandidates) - 1; i >= 0; i-- {
+		outEnergy := out.meanEnergy(outCandidates[i], length)
+		for _, mixIn := range inCandidates {
+			match := 1 - math.Min(1, math.Abs(outEnergy-in.meanEnergy(mixIn, length))/crossfadeEnergyRange)
+			if match > crossfade.EnergyMatch {
+				crossfade.MixOut = outCandidates[i]
+				crossfade.MixIn = mixIn
+				crossfade.EnergyMatch = match
+			}
+		}
+	}
+
+	outAnalysis, err := AnalyzeRange(a, crossfade.MixOut, crossfade.MixOut+length)
+	if err != nil {
+		return nil, err
+	}
+	inAnalysis, err := AnalyzeRange(b, crossfade.MixIn, crossfade.MixIn+length)
+	if err != nil {
+		return nil, err
+	}
+	crossfade.Distance = Distance(outAnalysis.ForceVector, inAnalysis.ForceVector)
+	similarity := 1 / (1 + float64(crossfade.Distance)/crossfadeDistanceScale)
+	crossfade.Quality = (similarity + crossfade.EnergyMatch) / 2
+	return crossfade, nil
+}
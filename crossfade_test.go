@@ -0,0 +1,36 @@
+package bliss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSuggestCrossfade(t *testing.T) {
+	const rate = 22050
+	// a: 40s of clicks at 120 BPM, getting quieter in its last 10s, then 2s of silence
+	a := clicks(rate, 40*rate, 120)
+	for i := 30 * rate; i < len(a); i++ {
+		a[i] *= 0.1
+	}
+	a = append(a, make([]float64, 2*rate)...)
+	// b: 1s of silence, then 40s of quiet clicks at 120 BPM
+	b := make([]float64, rate)
+	for _, v := range clicks(rate, 40*rate, 120) {
+		b = append(b, 0.1*v)
+	}
+
+	crossfade, err := SuggestCrossfade(newTestSong(rate, 1, a), newTestSong(rate, 1, b), CrossfadeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, int(8*time.Second), int(crossfade.Length), "crossfade length")
+	if crossfade.MixOut < 30*time.Second || crossfade.MixOut+crossfade.Length > 40*time.Second+100*time.Millisecond {
+		t.Errorf("expected to mix out in the quiet end of a, got: %v", crossfade.MixOut)
+	}
+	if crossfade.MixIn < time.Second-100*time.Millisecond || crossfade.MixIn > 2*time.Second {
+		t.Errorf("expected to mix in at the start of b, got: %v", crossfade.MixIn)
+	}
+	if crossfade.EnergyMatch < 0.9 || crossfade.Quality <= 0 || crossfade.Quality > 1 {
+		t.Errorf("unexpected crossfade scores: %+v", crossfade)
+	}
+}
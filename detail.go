@@ -0,0 +1,299 @@
+package bliss
+
+import (
+	"math"
+	"math/cmplx"
+	"sort"
+	"time"
+)
+
+const (
+	// envelopeRate is the number of envelope points per second.
+	envelopeRate = 100
+	// frequencyFrameSize is the DFT size used for frequency analyses.
+	frequencyFrameSize = 2048
+	// amplitudeBins is the number of bins of the amplitude histogram.
+	amplitudeBins = 100
+	// beatPeaks is the maximum number of dominant beats returned.
+	beatPeaks = 5
+	// minBeatFrequency and maxBeatFrequency bound the beat frequencies, in Hz (30 to 240 BPM).
+	minBeatFrequency = 0.5
+	maxBeatFrequency = 4
+)
+
+/*
+BeatPeak is a peak of the DFT of the envelope of a Song, corresponding to a dominant beat.
+*/
+type BeatPeak struct {
+	/*
+		Frequency is the frequency of the beat in Hz.
+	*/
+	Frequency float64
+	/*
+		Period is the duration between two beats.
+	*/
+	Period time.Duration
+	/*
+		Magnitude is the magnitude of the peak in the envelope DFT.
+	*/
+	Magnitude float64
+}
+
+/*
+EnvelopeAnalysis stores the envelope of a Song, its DFT, and the dominant beats found in it,
+e.g. for visualizations.
+
+The analysis follows the method described in EnvelopeSort, but is computed in Go with its
+own parameters (100 envelope points per second): it is not the analysis behind the tempo
+and attack ratings of the bliss library, which it does not reproduce.
+*/
+type EnvelopeAnalysis struct {
+	/*
+		Curve is the envelope of the song: the mean absolute value of its samples,
+		Rate times per second, smoothed.
+	*/
+	Curve []float64
+	/*
+		Rate is the number of envelope points per second.
+	*/
+	Rate float64
+	/*
+		Spectrum is the magnitude of the DFT of the envelope (with its mean removed),
+		from 0 Hz to Rate/2 Hz.
+	*/
+	Spectrum []float64
+	/*
+		Resolution is the frequency step between two Spectrum values, in Hz.
+	*/
+	Resolution float64
+	/*
+		Peaks are the dominant beats of the song, by decreasing magnitude.
+		Only beats between 30 and 240 beats per minute are considered.
+	*/
+	Peaks []BeatPeak
+}
+
+/*
+AmplitudeHistogram stores the histogram of the absolute values of the samples of a Song,
+e.g. for visualizations.
+
+The histogram is computed in Go with its own parameters (100 bins): it is not the histogram
+behind the amplitude rating of the bliss library (see AmplitudeSort), which it does not reproduce.
+*/
+type AmplitudeHistogram struct {
+	/*
+		Histogram counts the samples of the song by absolute value: bin i counts
+		the samples whose absolute value, scaled to [0, 1], is in [i*BinWidth, (i+1)*BinWidth).
+	*/
+	Histogram []uint64
+	/*
+		BinWidth is the width of a Histogram bin.
+	*/
+	BinWidth float64
+}
+
+/*
+FrequencyBands stores the energy in dB of each frequency band of a Song.
+
+The bands are: low (below 250 Hz), mid-low (250 Hz to 1 kHz), mid (1 to 2 kHz),
+mid-high (2 to 4 kHz), and high (above 4 kHz).
+*/
+type FrequencyBands struct {
+	Low     float64
+	MidLow  float64
+	Mid     float64
+	MidHigh float64
+	High    float64
+}
+
+var frequencyBandEdges = [...]float64{250, 1000, 2000, 4000}
+
+/*
+SpectrumAnalysis stores the mean power spectrum of a Song and the energy of its frequency
+bands, e.g. for visualizations.
+
+The analysis follows the method described in FrequencySort, but is computed in Go with its
+own parameters (2048-point DFT frames, band edges at 250, 1000, 2000 and 4000 Hz): it is not
+the analysis behind the frequency rating of the bliss library, which it does not reproduce.
+*/
+type SpectrumAnalysis struct {
+	/*
+		Bands stores the energy of each frequency band.
+	*/
+	Bands FrequencyBands
+	/*
+		Spectrum is the mean power spectrum of the song in dB, from 0 Hz to SampleRate/2 Hz.
+	*/
+	Spectrum []float64
+	/*
+		Resolution is the frequency step between two Spectrum values, in Hz.
+	*/
+	Resolution float64
+}
+
+func envelopeHop(sampleRate int) int {
+	if sampleRate < envelopeRate {
+		return 1
+	}
+	return sampleRate / envelopeRate
+}
+
+func envelopeOf(mono []float64, sampleRate int) []float64 {
+	hop := envelopeHop(sampleRate)
+	envelope := make([]float64, len(mono)/hop)
+	for i := range envelope {
+		var sum float64
+		for _, v := range mono[i*hop : (i+1)*hop] {
+			sum += math.Abs(v)
+		}
+		envelope[i] = sum / float64(hop)
+	}
+	return movingAverage(envelope, 5)
+}
+
+func envelopeSpectrum(envelope []float64) []float64 {
+	var mean float64
+	for _, v := range envelope {
+		mean += v
+	}
+	mean /= float64(len(envelope))
+	buffer := make([]complex128, nextPowerOfTwo(len(envelope)))
+	for i, v := range envelope {
+		buffer[i] = complex(v-mean, 0)
+	}
+	fft(buffer)
+	spectrum := make([]float64, len(buffer)/2+1)
+	for i := range spectrum {
+		spectrum[i] = cmplx.Abs(buffer[i])
+	}
+	return spectrum
+}
+
+func beatPeaksOf(spectrum []float64, resolution float64) []BeatPeak {
+	var peaks []BeatPeak
+	for i := 1; i < len(spectrum)-1; i++ {
+		frequency := float64(i) * resolution
+		if frequency < minBeatFrequency || frequency > maxBeatFrequency {
+			continue
+		}
+		if spectrum[i] > spectrum[i-1] && spectrum[i] >= spectrum[i+1] {
+			peaks = append(peaks, BeatPeak{
+				Frequency: frequency,
+				Period:    time.Duration(float64(time.Second) / frequency),
+				Magnitude: spectrum[i],
+			})
+		}
+	}
+	sort.SliceStable(peaks, func(i, j int) bool {
+		return peaks[i].Magnitude > peaks[j].Magnitude
+	})
+	if len(peaks) > beatPeaks {
+		peaks = peaks[:beatPeaks]
+	}
+	return peaks
+}
+
+// meanSpectrum returns the mean power spectrum of the samples and its resolution in Hz,
+// as used by AnalyzeSpectrum.
+func meanSpectrum(mono []float64, sampleRate int) ([]float64, float64) {
+	return averageSpectrum(mono, frequencyFrameSize), float64(sampleRate) / frequencyFrameSize
+}
+
+/*
+AnalyzeEnvelope computes the envelope of a Song, its DFT, and the dominant beats found in it.
+Unlike EnvelopeSort, it does not use the bliss library (see EnvelopeAnalysis).
+
+For songs shorter than two envelope points (20 ms), Spectrum and Peaks are empty.
+
+If the Song has no samples, AnalyzeEnvelope returns ErrNoSamples.
+*/
+func AnalyzeEnvelope(song *Song) (*EnvelopeAnalysis, error) {
+	mono, err := song.monoSamples()
+	if err != nil {
+		return nil, err
+	}
+	curve := envelopeOf(mono, song.SampleRate)
+	rate := float64(song.SampleRate) / float64(envelopeHop(song.SampleRate))
+	analysis := &EnvelopeAnalysis{
+		Curve: curve,
+		Rate:  rate,
+	}
+	if len(curve) >= 2 {
+		analysis.Spectrum = envelopeSpectrum(curve)
+		analysis.Resolution = rate / float64(2*(len(analysis.Spectrum)-1))
+		analysis.Peaks = beatPeaksOf(analysis.Spectrum, analysis.Resolution)
+	}
+	return analysis, nil
+}
+
+/*
+ComputeAmplitudeHistogram computes the histogram of the absolute values of the samples
+of a Song. Unlike AmplitudeSort, it does not use the bliss library (see AmplitudeHistogram).
+
+If the Song has no samples, ComputeAmplitudeHistogram returns ErrNoSamples.
+*/
+func ComputeAmplitudeHistogram(song *Song) (*AmplitudeHistogram, error) {
+	channels, err := song.channelSamples()
+	if err != nil {
+		return nil, err
+	}
+	histogram := &AmplitudeHistogram{
+		Histogram: make([]uint64, amplitudeBins),
+		BinWidth:  1 / float64(amplitudeBins),
+	}
+	for _, channel := range channels {
+		for _, v := range channel {
+			bin := int(math.Abs(v) * amplitudeBins)
+			if bin >= amplitudeBins {
+				bin = amplitudeBins - 1
+			}
+			histogram.Histogram[bin]++
+		}
+	}
+	return histogram, nil
+}
+
+/*
+AnalyzeSpectrum computes the mean power spectrum of a Song and the energy of each of its
+frequency bands. Unlike FrequencySort, it does not use the bliss library (see SpectrumAnalysis).
+
+If the Song has no samples, AnalyzeSpectrum returns ErrNoSamples.
+*/
+func AnalyzeSpectrum(song *Song) (*SpectrumAnalysis, error) {
+	mono, err := song.monoSamples()
+	if err != nil {
+		return nil, err
+	}
+	power, resolution := meanSpectrum(mono, song.SampleRate)
+
+	var bands [len(frequencyBandEdges) + 1]float64
+	var counts [len(bands)]int
+	for i, p := range power {
+		band := sort.SearchFloat64s(frequencyBandEdges[:], float64(i)*resolution)
+		bands[band] += p
+		counts[band]++
+	}
+	for i := range bands {
+		if counts[i] > 0 {
+			bands[i] /= float64(counts[i])
+		}
+		bands[i] = decibels(bands[i])
+	}
+
+	spectrum := make([]float64, len(power))
+	for i, p := range power {
+		spectrum[i] = decibels(p)
+	}
+	analysis := &SpectrumAnalysis{
+		Bands: FrequencyBands{
+			Low:     bands[0],
+			MidLow:  bands[1],
+			Mid:     bands[2],
+			MidHigh: bands[3],
+			High:    bands[4],
+		},
+		Spectrum:   spectrum,
+		Resolution: resolution,
+	}
+	return analysis, nil
+}
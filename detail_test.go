@@ -0,0 +1,66 @@
+package bliss
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAnalyzeEnvelopeAndSpectrum(t *testing.T) {
+	const rate = 22050
+	// a 100 Hz tone pulsing twice per second
+	samples := sine(rate, 10*rate, 100, 0.5)
+	for i := range samples {
+		if (i/(rate/4))%2 == 1 {
+			samples[i] = 0
+		}
+	}
+	song := newTestSong(rate, 1, samples)
+
+	envelope, err := AnalyzeEnvelope(song)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, len(samples)/envelopeHop(rate), len(envelope.Curve), "envelope length")
+	if len(envelope.Peaks) == 0 || math.Abs(envelope.Peaks[0].Frequency-2) > 0.05 {
+		t.Errorf("expected a dominant beat at 2 Hz, got: %v", envelope.Peaks)
+	}
+
+	amplitude, err := ComputeAmplitudeHistogram(song)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total uint64
+	for _, count := range amplitude.Histogram {
+		total += count
+	}
+	assertInt(t, len(samples), int(total), "histogram samples count")
+	if amplitude.Histogram[0] < uint64(len(samples)/2) {
+		t.Errorf("expected half of the samples in the silent bin, got: %d", amplitude.Histogram[0])
+	}
+
+	frequency, err := AnalyzeSpectrum(song)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bands := frequency.Bands
+	if bands.Low < bands.MidLow || bands.Low < bands.High {
+		t.Errorf("expected the low band to be the loudest, got: %+v", bands)
+	}
+}
+
+func TestNoSamples(t *testing.T) {
+	if _, err := AnalyzeEnvelope(&Song{}); err != ErrNoSamples {
+		t.Errorf("expected ErrNoSamples, got: %v", err)
+	}
+}
+
+func TestAnalyzeEnvelopeShort(t *testing.T) {
+	const rate = 22050
+	envelope, err := AnalyzeEnvelope(newTestSong(rate, 1, sine(rate, rate/100, 100, 0.5)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(envelope.Spectrum) != 0 || envelope.Resolution != 0 {
+		t.Errorf("expected no spectrum for a single envelope point, got: %d values, resolution %f", len(envelope.Spectrum), envelope.Resolution)
+	}
+}
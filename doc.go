@@ -15,7 +15,7 @@ go-bliss can compute:
 
 - for each song, a force (float) for the overall song intensity, corresponding to a force rating (ForceRating), either calm, loud, or unknown
 
-- for each song, a vector containg 4 floats (ForceVector), each rating an aspect of the song: tempo is the BPM of the song, amplitude is the physical force of the song, frequency is the ratio between high and low frequencies, attack is a sum of intensity of all the attacks divided by the song length
+- for each song, a vector containg 4 floats (ForceVector), each rating an aspect of the song: tempo is a rating of the beats of the song, amplitude is the physical force of the song, frequency is the ratio between high and low frequencies, attack is a sum of intensity of all the attacks divided by the song length
 
 - for two songs, the distance between them (float) (the euclidian distance between their force vectors), or their cosine similarity
 
@@ -29,9 +29,55 @@ A Song owns internal memory that can be freed explicitly by using Close when don
 
 go-bliss can analyze an audio file with Analyze into a Song, like Decode, except it also analyzes the song and fills its analysis-related fields.
 
+DecodeContext and AnalyzeContext are variants of Decode and Analyze that return as soon as their context is done. The native call cannot be interrupted and keeps running in the background; AbandonedCalls counts such calls.
+
 go-bliss can compute the distance between two songs (either audio files with DistanceFile, or files already decoded to songs with Distance), or their cosine similarity (with CosineSimilarity and CosineSimilarityFile).
 
-go-bliss can also compute a specific value of a song rather than all of them with EnvelopeSort, AmplitudeSort, and FrequencySort.
+go-bliss can also compute a specific value of a song rather than all of them with EnvelopeSort, AmplitudeSort, and FrequencySort. AnalyzeEnvelope, ComputeAmplitudeHistogram and AnalyzeSpectrum compute similar analyses in Go (envelope curve and its DFT, amplitude histogram, frequency bands), which is useful for visualizations; they are not the intermediate results of the native ratings, which they do not reproduce.
+
+EstimateTempo estimates the actual tempo of a song in beats per minute, with a confidence and half and double tempo alternatives. DetectOnsets and DetectBeats return the times of the onsets and the beat grid (beats and guessed downbeats) of a song.
+
+AnalyzeWindows runs the analysis over sliding windows of a decoded song, returning a time series of forces and force vectors; AnalyzeRange analyzes a single time range.
+
+MeasureLoudness measures the integrated loudness, loudness range, sample peak and true peak of a decoded song per EBU R128 / ITU-R BS.1770, as well as its ReplayGain track gain; AlbumGain computes the ReplayGain album gain of several songs.
+
+DetectSilence finds the leading, trailing and interior silences of a decoded song; AnalyzeAudible analyzes only its non-silent part.
+
+SuggestCrossfade suggests mix-out and mix-in points between two decoded songs from their energy curves, silences and beats, with a transition quality score.
+
+ExtractFeatures computes an extended set of spectral features of a decoded song (spectral centroid, rolloff and flatness, zero-crossing rate, MFCC means and variances, chroma) as a FeatureVector, a vector of named dimensions. Force vectors can be converted to feature vectors, and feature vectors can be compared with FeatureDistance and FeatureCosineSimilarity, and standardized with a Normalizer.
+
+DetectKey estimates the musical key and mode of a decoded song, with its Camelot wheel code, for harmonic mixing.
+
+AnalyzeStereo measures how a stereo song uses the stereo field (channel correlation, mid/side ratio, width, balance) and detects dual mono songs.
+
+DetectTranscode estimates the effective bandwidth of a decoded song from its spectrum, and flags songs likely transcoded from lossy files.
+
+AnalyzeDynamics counts the clipped samples of a decoded song, and measures its dynamic range per channel like DR meters do.
+
+ComputeWaveform computes the min/max peaks of a decoded song in the audiowaveform JSON format, and ComputeSpectrogram computes its spectrogram, which can be rendered as a PNG image.
+
+SelectExcerpt selects the most characteristic excerpt of a decoded song, e.g. for previews, and WriteWAV writes a range of a decoded song as a WAV file.
+
+ComputeSelfSimilarity computes the self-similarity matrix of a decoded song, and AnalyzeStructure segments it into labelled sections (intro, repeated sections, outro), rating each section like Analyze.
+
+ComputeFingerprint computes a compact fingerprint of the audio of a decoded song, and FindDuplicates finds groups of likely duplicates among analyzed songs by combining their fingerprints, force vectors and tags.
+
+Index stores analyzed songs to find the songs closest to a force vector (Nearest), or to an audio snippet read from a file or a reader (QueryFile, QueryReader). FeatureIndex does the same for feature vectors, such as extended features standardized with a Normalizer.
+
+Explain explains the distance and cosine similarity between two force vectors, with the contribution of each dimension and a human-readable summary such as "B is much brighter and slightly faster". An Explainer fitted to a library scales the thresholds of the descriptors to the spread of each dimension.
+
+FitTagger fits a Tagger to the force vectors of a library, to map force vectors to human-readable tags such as fast, bright or punchy.
+
+FitCalibration fits a Calibration to the forces of a library, to rate songs relatively to the library rather than relatively to a force of 0.
+
+Project projects force vectors to 2-D with PCA or classical MDS, e.g. to draw a mood map of a library, which can be rendered as an SVG scatter plot.
+
+NewReport computes statistics about an analyzed library, such as the distribution of each rating, per-genre and per-artist means, outliers and analysis failures, which can be written as JSON or as a self-contained HTML page.
+
+Analyzers
+
+Analyzer abstracts Analyze so that analysis behaviour can be composed: DefaultAnalyzer can be wrapped with Chain and middlewares that cache results (Cache), retry transient I/O errors (Retry), limit the time spent per file (Timeout), limit concurrency (Limit), and record durations and outcomes (Instrument).
 
 Misc
 
@@ -40,5 +86,9 @@ go-bliss also has Mean, Variance, and RectangularFilter helpers, as well as a Ve
 Multi-threaded use
 
 As far as I know bliss does not store global state, so processing two different songs concurrently should be fine.
+
+Crash isolation
+
+A malformed file can crash the native decoder, taking down the whole process. WorkerPool is an Analyzer that runs analyses in child processes instead (the program must call ServeWorker at startup), restarting crashed workers and enforcing per-file timeouts and memory limits.
 */
 package bliss
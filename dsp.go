@@ -0,0 +1,117 @@
+package bliss
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// fft computes the DFT of x in place. len(x) must be a power of two.
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := x[start+k], w*x[start+k+size/2]
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				w *= step
+			}
+		}
+	}
+}
+
+func hannWindow(n int) []float64 {
+	window := make([]float64, n)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+	}
+	return window
+}
+
+// forEachSpectrum calls f with the power spectrum (size/2+1 bins) of each Hann-windowed
+// frame of x, frames starting every hop samples (at least 1). The power slice is reused
+// between calls.
+func forEachSpectrum(x []float64, size int, hop int, f func(frame int, power []float64)) {
+	if hop < 1 {
+		hop = 1
+	}
+	window := hannWindow(size)
+	buffer := make([]complex128, size)
+	power := make([]float64, size/2+1)
+	for frame, start := 0, 0; start+size <= len(x) || (frame == 0 && len(x) > 0); frame, start = frame+1, start+hop {
+		for i := range buffer {
+			var v float64
+			if start+i < len(x) {
+				v = x[start+i] * window[i]
+			}
+			buffer[i] = complex(v, 0)
+		}
+		fft(buffer)
+		for i := range power {
+			r, im := real(buffer[i]), imag(buffer[i])
+			power[i] = r*r + im*im
+		}
+		f(frame, power)
+	}
+}
+
+// averageSpectrum returns the mean power spectrum of the frames of x.
+func averageSpectrum(x []float64, size int) []float64 {
+	average := make([]float64, size/2+1)
+	frames := 0
+	forEachSpectrum(x, size, size/2, func(frame int, power []float64) {
+		for i, p := range power {
+			average[i] += p
+		}
+		frames++
+	})
+	for i := range average {
+		average[i] /= float64(frames)
+	}
+	return average
+}
+
+func decibels(power float64) float64 {
+	return 10 * math.Log10(power+1e-12)
+}
+
+// movingAverage smoothes x with a centered window of width points.
+func movingAverage(x []float64, width int) []float64 {
+	smoothed := make([]float64, len(x))
+	half := width / 2
+	var sum float64
+	count := 0
+	for i := -half; i < len(x); i++ {
+		if j := i + half; j < len(x) {
+			sum += x[j]
+			count++
+		}
+		if j := i - half - 1; j >= 0 {
+			sum -= x[j]
+			count--
+		}
+		if i >= 0 {
+			smoothed[i] = sum / float64(count)
+		}
+	}
+	return smoothed
+}
@@ -0,0 +1,376 @@
+package bliss
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// defaultDuplicateThreshold is the default minimum score of duplicates.
+	defaultDuplicateThreshold = 0.45
+	// defaultDuplicateOffset is the default maximum offset between the audio of duplicates.
+	defaultDuplicateOffset = 10 * time.Second
+	// weights of the similarities in duplicate scores
+	fingerprintWeight = 0.5
+	tagsWeight        = 0.3
+	forceWeight       = 0.2
+	// maxFrameSongs is the number of songs from which a fingerprint frame value is too common
+	// to block songs, e.g. the frames of silences.
+	maxFrameSongs = 10
+	// minSharedFrames is the number of distinct frame values two fingerprints must share
+	// for their songs to be compared: unrelated songs share a few frame values by chance.
+	minSharedFrames = 16
+)
+
+/*
+DuplicateCandidate is a song to compare by FindDuplicates.
+*/
+type DuplicateCandidate struct {
+	/*
+		Song is the analyzed song. Its samples are not used, so it can be e.g. a Song
+		returned by Cache or WorkerPool.
+	*/
+	Song *Song
+	/*
+		Fingerprint is the fingerprint of the song, computed with ComputeFingerprint,
+		or nil if unknown.
+	*/
+	Fingerprint *Fingerprint
+}
+
+/*
+DuplicateOptions stores the options of FindDuplicates.
+*/
+type DuplicateOptions struct {
+	/*
+		Threshold is the minimum score of two songs for them to be duplicates, between 0 and 1.
+		Defaults to 0.45.
+	*/
+	Threshold float64
+	/*
+		MaxOffset is the maximum offset between the audio of two copies of a recording, e.g.
+		because of different leading silences. Defaults to 10 seconds.
+	*/
+	MaxOffset time.Duration
+}
+
+/*
+DuplicateScore stores the similarities between two songs.
+*/
+type DuplicateScore struct {
+	/*
+		Fingerprint is the similarity of the fingerprints of the songs (see
+		FingerprintSimilarity), or -1 if a fingerprint is unknown.
+	*/
+	Fingerprint float64
+	/*
+		Offset is the offset of the audio of the second song relatively to the first song,
+		as returned by FingerprintSimilarity.
+	*/
+	Offset time.Duration
+	/*
+		Tags is the similarity of the tags of the songs: 1 if their normalized artists and
+		titles are equal, 0.5 if only their titles are equal, 0 otherwise, or -1 if a title is empty.
+		Normalized tags ignore case, punctuation, and version details in parentheses,
+		brackets, after a dash, or after "feat.", e.g. "Song (Remastered 2011)" and "song"
+		are equal.
+	*/
+	Tags float64
+	/*
+		Force is the similarity of the ForceVector of the songs, between 0 and 1:
+		1/(1+d), d being the Distance between them.
+	*/
+	Force float64
+	/*
+		Score is the weighted mean of the known similarities, between 0 and 1, with weights
+		0.5 for Fingerprint, 0.3 for Tags and 0.2 for Force.
+	*/
+	Score float64
+}
+
+/*
+DuplicatePair is a pair of duplicates found by FindDuplicates.
+*/
+type DuplicatePair struct {
+	/*
+		A and B are the indices of the songs in the candidates, A < B.
+	*/
+	A, B int
+	/*
+		Score stores the similarities of the songs.
+	*/
+	Score DuplicateScore
+}
+
+/*
+DuplicateGroup is a group of songs that are likely duplicates of each other.
+*/
+type DuplicateGroup struct {
+	/*
+		Songs stores the indices of the songs of the group in the candidates, in increasing order.
+	*/
+	Songs []int
+	/*
+		Pairs stores the pairs of duplicates linking the songs of the group.
+	*/
+	Pairs []DuplicatePair
+	/*
+		Score is the lowest score of the pairs of the group.
+	*/
+	Score float64
+}
+
+// normalizeTag returns a tag without case, punctuation, and version details.
+func normalizeTag(tag string) string {
+	tag = strings.ToLower(tag)
+	for _, separator := range []string{" - ", " feat.", " feat ", " ft.", " featuring "} {
+		if i := strings.Index(tag, separator); i > 0 {
+			tag = tag[:i]
+		}
+	}
+	var normalized strings.Builder
+	depth := 0
+	space := false
+	for _, r := range tag {
+		switch {
+		case r == '(' || r == '[':
+			depth++
+		case r == ')' || r == ']':
+			if depth > 0 {
+				depth--
+			}
+		case depth > 0:
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && normalized.Len() > 0 {
+				normalized.WriteByte(' ')
+			}
+			space = false
+			normalized.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return normalized.String()
+}
+
+// duplicateTags stores the normalized tags of a song, to compare them without normalizing
+// them for each pair of songs.
+type duplicateTags struct {
+	song          *Song
+	title, artist string
+}
+
+func newDuplicateTags(song *Song) duplicateTags {
+	return duplicateTags{
+		song:   song,
+		title:  normalizeTag(song.Title),
+		artist: normalizeTag(song.Artist),
+	}
+}
+
+// forceDistance is Distance computed in Go, to avoid a cgo call per pair of songs.
+func forceDistance(a ForceVector, b ForceVector) float64 {
+	x, y := forceValues(a), forceValues(b)
+	var sum float64
+	for i := range x {
+		sum += (x[i] - y[i]) * (x[i] - y[i])
+	}
+	return math.Sqrt(sum)
+}
+
+/*
+CompareDuplicates computes the similarities between two songs, as computed by FindDuplicates.
+*/
+func CompareDuplicates(a DuplicateCandidate, b DuplicateCandidate, maxOffset time.Duration) DuplicateScore {
+	score := compareWithoutFingerprints(newDuplicateTags(a.Song), newDuplicateTags(b.Song))
+	if a.Fingerprint != nil && b.Fingerprint != nil {
+		score.Fingerprint, score.Offset = FingerprintSimilarity(a.Fingerprint, b.Fingerprint, maxOffset)
+	}
+	score.Score = score.weighted(score.Fingerprint)
+	return score
+}
+
+func compareWithoutFingerprints(a duplicateTags, b duplicateTags) DuplicateScore {
+	score := DuplicateScore{
+		Fingerprint: -1,
+		Tags:        -1,
+		Force:       1 / (1 + forceDistance(a.song.ForceVector, b.song.ForceVector)),
+	}
+	if a.title != "" && b.title != "" {
+		switch {
+		case a.title != b.title:
+			score.Tags = 0
+		case a.artist == b.artist:
+			score.Tags = 1
+		default:
+			score.Tags = 0.5
+		}
+	}
+	return score
+}
+
+// duplicateBlocks returns the pairs of candidates sharing a normalized title or
+// minSharedFrames frame values of their fingerprints, in increasing order.
+func duplicateBlocks(candidates []DuplicateCandidate, tags []duplicateTags) [][2]int {
+	blocks := make(map[string][]int)
+	for i := range tags {
+		if tags[i].title != "" {
+			blocks[tags[i].title] = append(blocks[tags[i].title], i)
+		}
+	}
+	frames := make(map[uint32][]int)
+	for i, candidate := range candidates {
+		if candidate.Fingerprint == nil {
+			continue
+		}
+		for _, frame := range candidate.Fingerprint.Frames {
+			songs := frames[frame]
+			if len(songs) == 0 || songs[len(songs)-1] != i {
+				frames[frame] = append(songs, i)
+			}
+		}
+	}
+
+	shared := make(map[[2]int]int)
+	for _, songs := range frames {
+		if len(songs) > maxFrameSongs {
+			continue
+		}
+		for x, i := range songs {
+			for _, j := range songs[x+1:] {
+				shared[[2]int{i, j}]++
+			}
+		}
+	}
+	for _, songs := range blocks {
+		for x, i := range songs {
+			for _, j := range songs[x+1:] {
+				shared[[2]int{i, j}] = minSharedFrames
+			}
+		}
+	}
+	var pairs [][2]int
+	for pair, n := range shared {
+		if n >= minSharedFrames {
+			pairs = append(pairs, pair)
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	return pairs
+}
+
+// weighted returns the score of the similarities, with the given fingerprint similarity.
+func (score DuplicateScore) weighted(fingerprint float64) float64 {
+	sum, weights := forceWeight*score.Force, forceWeight
+	if score.Tags >= 0 {
+		sum += tagsWeight * score.Tags
+		weights += tagsWeight
+	}
+	if fingerprint >= 0 {
+		sum += fingerprintWeight * fingerprint
+		weights += fingerprintWeight
+	}
+	return sum / weights
+}
+
+/*
+FindDuplicates finds groups of likely duplicates among songs, e.g. copies of the same
+recording from different releases or encodings, or alternate versions of a song.
+
+Songs are compared with CompareDuplicates, and two songs are duplicates if their score is
+at least the threshold; groups are the sets of songs linked by duplicate pairs. Groups are
+returned in order of their first song.
+
+Only pairs of songs sharing a normalized title, or at least 16 frame values of their
+fingerprints, are compared, so that large libraries can be processed: copies of a recording share many
+exact frame values, even across encodings, whereas unrelated songs rarely do. Frame values
+common to many songs, such as the frames of silences, are ignored. Pairs of songs without
+such evidence are never duplicates, whatever their force similarity.
+*/
+func FindDuplicates(candidates []DuplicateCandidate, options DuplicateOptions) []DuplicateGroup {
+	threshold := options.Threshold
+	if threshold == 0 {
+		threshold = defaultDuplicateThreshold
+	}
+	maxOffset := options.MaxOffset
+	if maxOffset == 0 {
+		maxOffset = defaultDuplicateOffset
+	}
+
+	// union-find of the groups
+	parents := make([]int, len(candidates))
+	for i := range parents {
+		parents[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parents[i] != i {
+			parents[i] = find(parents[i])
+		}
+		return parents[i]
+	}
+
+	tags := make([]duplicateTags, len(candidates))
+	for i, candidate := range candidates {
+		tags[i] = newDuplicateTags(candidate.Song)
+	}
+	var pairs []DuplicatePair
+	for _, block := range duplicateBlocks(candidates, tags) {
+		i, j := block[0], block[1]
+		a, b := candidates[i], candidates[j]
+		score := compareWithoutFingerprints(tags[i], tags[j])
+		if a.Fingerprint != nil && b.Fingerprint != nil {
+			if score.weighted(1) < threshold {
+				continue
+			}
+			score.Fingerprint, score.Offset = FingerprintSimilarity(a.Fingerprint, b.Fingerprint, maxOffset)
+		}
+		if score.Fingerprint < 0 && score.Tags < 0 {
+			// the force similarity alone is not evidence of a duplicate
+			continue
+		}
+		score.Score = score.weighted(score.Fingerprint)
+		if score.Score < threshold {
+			continue
+		}
+		pairs = append(pairs, DuplicatePair{A: i, B: j, Score: score})
+		parents[find(j)] = find(i)
+	}
+
+	groups := make(map[int]*DuplicateGroup)
+	for _, pair := range pairs {
+		root := find(pair.A)
+		group, ok := groups[root]
+		if !ok {
+			group = &DuplicateGroup{
+				Score: 1,
+			}
+			groups[root] = group
+		}
+		group.Pairs = append(group.Pairs, pair)
+		if pair.Score.Score < group.Score {
+			group.Score = pair.Score.Score
+		}
+	}
+	var result []DuplicateGroup
+	for i := range candidates {
+		if group, ok := groups[find(i)]; ok {
+			group.Songs = append(group.Songs, i)
+		}
+	}
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Songs[0] < result[j].Songs[0]
+	})
+	return result
+}
@@ -0,0 +1,88 @@
+package bliss
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	for tag, normalized := range map[string]string{
+		"Song (Remastered 2011)": "song",
+		"Song - 2011 Remaster":   "song",
+		"SONG [Live]":            "song",
+		"Song feat. Someone":     "song",
+		"  Don't   Stop!  ":      "don t stop",
+		"Ünïcode Sóng":           "ünïcode sóng",
+	} {
+		if n := normalizeTag(tag); n != normalized {
+			t.Errorf("tag %q: expected %q, got: %q", tag, normalized, n)
+		}
+	}
+}
+
+func TestForceDistance(t *testing.T) {
+	a := ForceVector{Tempo: 1, Attack: 2, Amplitude: -1, Frequency: 0.5}
+	b := ForceVector{Tempo: 4, Attack: 2, Amplitude: 3, Frequency: 0.5}
+	assertNear(t, 5, forceDistance(a, b), 1e-6, "force distance")
+}
+
+func TestFindDuplicates(t *testing.T) {
+	const rate = 22050
+	original := melody(rate, 20, 1)
+	quieter := make([]float64, rate)
+	for _, v := range original {
+		quieter = append(quieter, 0.5*v)
+	}
+	songs := []*Song{
+		newTestSong(rate, 1, original),
+		newTestSong(rate, 1, melody(rate, 20, 2)),
+		newTestSong(rate, 1, quieter),
+		newTestSong(rate, 1, melody(rate, 20, 3)),
+		newTestSong(rate, 1, melody(rate, 20, 4)),
+		newTestSong(rate, 1, melody(rate, 20, 5)),
+	}
+	songs[0].Artist, songs[0].Title = "Artist", "Song (Remastered 2011)"
+	songs[1].Artist, songs[1].Title = "Artist", "Other song"
+	// untagged copy of the first song
+	songs[2].ForceVector = ForceVector{Tempo: 0.1}
+	// live version of the first song
+	songs[3].Artist, songs[3].Title = "ARTIST", "Song - Live"
+	songs[3].ForceVector = ForceVector{Tempo: 0.5}
+	// unrelated untagged song
+	songs[4].ForceVector = ForceVector{Tempo: 5, Amplitude: 5}
+	// unrelated untagged song with the same force vector
+	songs[5].ForceVector = songs[4].ForceVector
+
+	candidates := make([]DuplicateCandidate, len(songs))
+	for i, song := range songs {
+		fingerprint, err := ComputeFingerprint(song)
+		if err != nil {
+			t.Fatal(err)
+		}
+		candidates[i] = DuplicateCandidate{Song: song, Fingerprint: fingerprint}
+	}
+	blocks := duplicateBlocks(candidates, make([]duplicateTags, len(candidates)))
+	if !reflect.DeepEqual(blocks, [][2]int{{0, 2}}) {
+		t.Errorf("expected only the copies to share fingerprint frames, got: %v", blocks)
+	}
+	groups := FindDuplicates(candidates, DuplicateOptions{})
+	if len(groups) != 1 {
+		t.Fatalf("expected a single group, got: %+v", groups)
+	}
+	if !reflect.DeepEqual(groups[0].Songs, []int{0, 2, 3}) {
+		t.Errorf("unexpected group songs: %v", groups[0].Songs)
+	}
+	for _, pair := range groups[0].Pairs {
+		if pair.A == 0 && pair.B == 2 {
+			assertNear(t, 1, pair.Score.Offset.Seconds(), 0.15, "copy offset")
+			assertFloat(t, -1, float32(pair.Score.Tags), "copy tags similarity")
+		}
+	}
+
+	score := CompareDuplicates(candidates[0], candidates[1], time.Second)
+	assertFloat(t, 0, float32(score.Tags), "different titles tags similarity")
+	if score.Score >= defaultDuplicateThreshold {
+		t.Errorf("expected a low score for different songs, got: %f", score.Score)
+	}
+}
@@ -0,0 +1,147 @@
+package bliss
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// clippedRunLength is the minimum number of consecutive full-scale samples of a clipped run.
+	clippedRunLength = 3
+	// dynamicRangeBlock is the duration of the blocks of the DR meter.
+	dynamicRangeBlock = 3 * time.Second
+	// dynamicRangeLoudest is the share of the loudest blocks used by the DR meter.
+	dynamicRangeLoudest = 0.2
+)
+
+/*
+ChannelDynamics stores the clipping and dynamic range measures of a channel of a Song.
+*/
+type ChannelDynamics struct {
+	/*
+		ClippedSamples is the number of samples at full scale (the lowest or highest
+		value of the sample format).
+	*/
+	ClippedSamples int
+	/*
+		ClippedRuns is the number of runs of at least 3 consecutive full-scale samples,
+		which are very likely caused by clipping.
+	*/
+	ClippedRuns int
+	/*
+		LongestRun is the length of the longest run of consecutive full-scale samples.
+	*/
+	LongestRun int
+	/*
+		Peak is the highest absolute sample value, in dBFS.
+	*/
+	Peak float64
+	/*
+		RMS is the RMS level of the channel, in dBFS.
+	*/
+	RMS float64
+	/*
+		DynamicRange is the DR meter value of the channel, in dB: the difference between
+		the second highest peak of its 3 seconds blocks and the RMS level of its loudest 20% blocks.
+	*/
+	DynamicRange float64
+}
+
+/*
+Dynamics stores the clipping and dynamic range measures of a Song.
+*/
+type Dynamics struct {
+	/*
+		Channels stores the measures of each channel of the song.
+	*/
+	Channels []ChannelDynamics
+	/*
+		DynamicRange is the DR value of the song: the mean of the DynamicRange of its
+		channels, rounded to an integer, as displayed by DR meters. Heavily compressed
+		songs have a low value (under 8), very dynamic songs a high value (over 14).
+	*/
+	DynamicRange int
+	/*
+		ClippedRuns is the total number of clipped runs of all channels.
+	*/
+	ClippedRuns int
+}
+
+/*
+AnalyzeDynamics counts the clipped samples of a Song, and measures its dynamic range per
+channel like DR meters do, e.g. to reject over-compressed or clipped masters.
+
+If the Song has no samples, AnalyzeDynamics returns ErrNoSamples.
+*/
+func AnalyzeDynamics(song *Song) (*Dynamics, error) {
+	channels, err := song.channelSamples()
+	if err != nil {
+		return nil, err
+	}
+	// the highest sample value of the format, e.g. 32767/32768 for 16-bit samples
+	fullScale := 1 - 1/float64(int64(1)<<uint(8*song.BytesPerSample-1))
+	blockLength := int(dynamicRangeBlock.Seconds() * float64(song.SampleRate))
+
+	dynamics := &Dynamics{
+		Channels: make([]ChannelDynamics, len(channels)),
+	}
+	var dynamicRange float64
+	for c, channel := range channels {
+		d := &dynamics.Channels[c]
+		var run int
+		var peak, sum float64
+		var blockPeaks, blockPowers []float64
+		var blockPeak, blockSum float64
+		for i, v := range channel {
+			a := math.Abs(v)
+			if a >= fullScale {
+				d.ClippedSamples++
+				run++
+				if run == clippedRunLength {
+					d.ClippedRuns++
+				}
+				if run > d.LongestRun {
+					d.LongestRun = run
+				}
+			} else {
+				run = 0
+			}
+			peak = math.Max(peak, a)
+			sum += v * v
+
+			blockPeak = math.Max(blockPeak, a)
+			blockSum += v * v
+			if (i+1)%blockLength == 0 || i == len(channel)-1 {
+				n := (i % blockLength) + 1
+				blockPeaks = append(blockPeaks, blockPeak)
+				// twice the mean square, so that the RMS of a sine equals its peak
+				blockPowers = append(blockPowers, 2*blockSum/float64(n))
+				blockPeak, blockSum = 0, 0
+			}
+		}
+		d.Peak = 20 * math.Log10(peak)
+		d.RMS = 10 * math.Log10(sum/float64(len(channel)))
+
+		sort.Sort(sort.Reverse(sort.Float64Slice(blockPeaks)))
+		sort.Sort(sort.Reverse(sort.Float64Slice(blockPowers)))
+		secondPeak := blockPeaks[0]
+		if len(blockPeaks) > 1 {
+			secondPeak = blockPeaks[1]
+		}
+		loudest := int(math.Ceil(dynamicRangeLoudest * float64(len(blockPowers))))
+		var loudestPower float64
+		for _, power := range blockPowers[:loudest] {
+			loudestPower += power
+		}
+		loudestPower /= float64(loudest)
+		if loudestPower > 0 {
+			d.DynamicRange = 20*math.Log10(secondPeak) - 10*math.Log10(loudestPower)
+		}
+
+		dynamicRange += d.DynamicRange
+		dynamics.ClippedRuns += d.ClippedRuns
+	}
+	dynamics.DynamicRange = int(math.Round(dynamicRange / float64(len(channels))))
+	return dynamics, nil
+}
@@ -0,0 +1,39 @@
+package bliss
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAnalyzeDynamics(t *testing.T) {
+	const rate = 22050
+	// a quiet sine with a short transient peak every 3 seconds
+	samples := sine(rate, 12*rate, 440, 0.25)
+	for i := 0; i < len(samples); i += 3 * rate {
+		samples[i] = 0.5
+	}
+	dynamics, err := AnalyzeDynamics(newTestSong(rate, 1, samples))
+	if err != nil {
+		t.Fatal(err)
+	}
+	channel := dynamics.Channels[0]
+	assertInt(t, 0, channel.ClippedSamples, "clipped samples")
+	assertNear(t, 20*math.Log10(0.5), channel.Peak, 0.01, "peak")
+	assertNear(t, 20*math.Log10(0.25/math.Sqrt2), channel.RMS, 0.01, "rms")
+	assertNear(t, 20*math.Log10(0.5/0.25), channel.DynamicRange, 0.01, "channel dynamic range")
+	assertInt(t, 6, dynamics.DynamicRange, "dynamic range")
+
+	// a sine amplified 4 times, clipped twice per period
+	clipped := sine(rate, 3*rate, 100, 4)
+	for i, v := range clipped {
+		clipped[i] = math.Max(-1, math.Min(1, v))
+	}
+	dynamics, err = AnalyzeDynamics(newTestSong(rate, 1, clipped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, 2*3*100, dynamics.ClippedRuns, "clipped runs")
+	if dynamics.Channels[0].LongestRun < 70 {
+		t.Errorf("expected long clipped runs, got: %d", dynamics.Channels[0].LongestRun)
+	}
+}
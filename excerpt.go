@@ -0,0 +1,221 @@
+package bliss
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	// defaultExcerptLength and defaultExcerptHop are the default length and hop of excerpt windows.
+	defaultExcerptLength = 30 * time.Second
+	defaultExcerptHop    = 2 * time.Second
+	// excerptChromaStep is the duration of the chroma vectors compared to find repetitions.
+	excerptChromaStep = time.Second
+)
+
+/*
+ExcerptOptions stores the options of SelectExcerpt.
+*/
+type ExcerptOptions struct {
+	/*
+		Length is the length of the excerpt. Defaults to 30 seconds.
+	*/
+	Length time.Duration
+	/*
+		Hop is the duration between the start of two consecutive candidate excerpts.
+		Defaults to 2 seconds.
+	*/
+	Hop time.Duration
+}
+
+/*
+Excerpt stores a representative excerpt of a Song, e.g. to be used as a preview.
+*/
+type Excerpt struct {
+	/*
+		Start is the start of the excerpt, from the start of the song.
+	*/
+	Start time.Duration
+	/*
+		End is the end of the excerpt, from the start of the song.
+	*/
+	End time.Duration
+	/*
+		Similarity is how close the ForceVector of the excerpt is to the ForceVector of
+		the whole song, between 0 and 1: 1/(1+d), d being the Distance between them.
+	*/
+	Similarity float64
+	/*
+		Repetition is how much the excerpt is repeated elsewhere in the song (as choruses
+		are), relatively to the other candidate excerpts, between 0 and 1.
+	*/
+	Repetition float64
+	/*
+		Score is the score of the excerpt, between 0 and 1: the mean of Similarity and Repetition.
+	*/
+	Score float64
+}
+
+// rescale maps values linearly to [0, 1]; if all values are equal, they are mapped to 1.
+func rescale(values []float64) {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+	for i, v := range values {
+		if max > min {
+			values[i] = (v - min) / (max - min)
+		} else {
+			values[i] = 1
+		}
+	}
+}
+
+// repetition returns the highest mean similarity between the length chroma vectors
+// starting at start, and any other non-overlapping range of the chromagram.
+func repetition(chroma [][12]float64, start int, length int) float64 {
+	var best float64
+	for other := 0; other+length <= len(chroma); other++ {
+		if other > start-length && other < start+length {
+			continue
+		}
+		var sum float64
+		for i := 0; i < length; i++ {
+			sum += chromaSimilarity(&chroma[start+i], &chroma[other+i])
+		}
+		best = math.Max(best, sum/float64(length))
+	}
+	return best
+}
+
+/*
+SelectExcerpt selects the most characteristic excerpt of a Song, e.g. for previews.
+
+Candidate excerpts are sliding windows of the song (see AnalyzeWindows), scored by how
+close their ForceVector is to the ForceVector of the whole song, and by how much their
+chroma features (as computed for ExtractFeatures) are repeated elsewhere in the song,
+which favors choruses. The earliest best excerpt is returned.
+
+If the Song is shorter than the excerpt length, the whole song is returned.
+
+If the Song has no samples, SelectExcerpt returns ErrNoSamples.
+*/
+func SelectExcerpt(song *Song, options ExcerptOptions) (*Excerpt, error) {
+	length := options.Length
+	if length == 0 {
+		length = defaultExcerptLength
+	}
+	hop := options.Hop
+	if hop == 0 {
+		hop = defaultExcerptHop
+	}
+	windows, err := AnalyzeWindows(song, length, hop)
+	if err != nil {
+		return nil, err
+	}
+	mono, err := song.monoSamples()
+	if err != nil {
+		return nil, err
+	}
+	whole := rateSamples(song, song.sampleBytes())
+	chroma := chromagram(mono, song.SampleRate, excerptChromaStep)
+	steps := int(length / excerptChromaStep)
+
+	similarities := make([]float64, len(windows))
+	repetitions := make([]float64, len(windows))
+	for i, window := range windows {
+		similarities[i] = 1 / (1 + float64(Distance(window.ForceVector, whole)))
+		start := int(window.Start / excerptChromaStep)
+		n := steps
+		if n > len(chroma)-start {
+			n = len(chroma) - start
+		}
+		if n > 0 {
+			repetitions[i] = repetition(chroma, start, n)
+		}
+	}
+	rescale(repetitions)
+
+	var excerpt *Excerpt
+	for i, window := range windows {
+		score := (similarities[i] + repetitions[i]) / 2
+		if excerpt == nil || score > excerpt.Score {
+			excerpt = &Excerpt{
+				Start:      window.Start,
+				End:        window.End,
+				Similarity: similarities[i],
+				Repetition: repetitions[i],
+				Score:      score,
+			}
+		}
+	}
+	return excerpt, nil
+}
+
+/*
+WriteWAV writes the samples of a Song between start and end to w, as a PCM WAV file,
+e.g. to save an excerpt selected by SelectExcerpt.
+
+start and end are clamped to the song duration.
+
+If the Song has no samples, or the range is empty, WriteWAV returns ErrNoSamples.
+*/
+func WriteWAV(w io.Writer, song *Song, start time.Duration, end time.Duration) error {
+	from, to := song.frameAt(start), song.frameAt(end)
+	if song.SampleRate <= 0 || from >= to {
+		return ErrNoSamples
+	}
+	frameBytes := song.Channels * song.BytesPerSample
+	data := song.sampleBytes()[from*frameBytes : to*frameBytes]
+	// RIFF chunks are padded to an even size
+	padding := len(data) % 2
+	if uint64(len(data)+padding)+36 > math.MaxUint32 {
+		return errors.New("bliss: range too long for a WAV file")
+	}
+
+	header := struct {
+		RIFF          [4]byte
+		Size          uint32
+		WAVE          [4]byte
+		Format        [4]byte
+		FormatSize    uint32
+		AudioFormat   uint16
+		Channels      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+		Data          [4]byte
+		DataSize      uint32
+	}{
+		RIFF:          [4]byte{'R', 'I', 'F', 'F'},
+		Size:          uint32(36 + len(data) + padding),
+		WAVE:          [4]byte{'W', 'A', 'V', 'E'},
+		Format:        [4]byte{'f', 'm', 't', ' '},
+		FormatSize:    16,
+		AudioFormat:   1, // PCM
+		Channels:      uint16(song.Channels),
+		SampleRate:    uint32(song.SampleRate),
+		ByteRate:      uint32(song.SampleRate * frameBytes),
+		BlockAlign:    uint16(frameBytes),
+		BitsPerSample: uint16(8 * song.BytesPerSample),
+		Data:          [4]byte{'d', 'a', 't', 'a'},
+		DataSize:      uint32(len(data)),
+	}
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	// samples are already little-endian (and unsigned for 8-bit samples), as in WAV files
+	if err := binary.Write(w, binary.LittleEndian, data); err != nil {
+		return err
+	}
+	if padding != 0 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package bliss
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestSelectExcerpt(t *testing.T) {
+	const rate = 22050
+	section := func(frequencies ...float64) []float64 {
+		samples := make([]float64, 10*rate)
+		for _, frequency := range frequencies {
+			for i, v := range sine(rate, len(samples), frequency, 0.2) {
+				samples[i] += v
+			}
+		}
+		return samples
+	}
+	// intro, chorus, verse, chorus, outro
+	var samples []float64
+	samples = append(samples, section(196)...)
+	samples = append(samples, section(440, 554.37)...)
+	samples = append(samples, section(329.63)...)
+	samples = append(samples, section(440, 554.37)...)
+	samples = append(samples, section(369.99)...)
+
+	excerpt, err := SelectExcerpt(newTestSong(rate, 1, samples), ExcerptOptions{
+		Length: 10 * time.Second,
+		Hop:    time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if excerpt.Start != 10*time.Second && excerpt.Start != 30*time.Second {
+		t.Errorf("expected the chorus to be selected, got: %v", excerpt.Start)
+	}
+	assertNear(t, 1, excerpt.Repetition, 1e-9, "repetition")
+	assertNear(t, 10, (excerpt.End - excerpt.Start).Seconds(), 1e-3, "length")
+
+	excerpt, err = SelectExcerpt(newTestSong(rate, 1, samples[:5*rate]), ExcerptOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if excerpt.Start != 0 || excerpt.End != 5*time.Second {
+		t.Errorf("expected the whole song for a short song, got: %v-%v", excerpt.Start, excerpt.End)
+	}
+}
+
+func TestWriteWAV(t *testing.T) {
+	const rate = 22050
+	song := newTestSong(rate, 2, sine(rate, 2*rate, 440, 0.5))
+	var buffer bytes.Buffer
+	if err := WriteWAV(&buffer, song, 500*time.Millisecond, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	wav := buffer.Bytes()
+	if string(wav[0:4]) != "RIFF" || string(wav[8:12]) != "WAVE" || string(wav[36:40]) != "data" {
+		t.Fatalf("invalid WAV header: %q", wav[:44])
+	}
+	dataSize := int(binary.LittleEndian.Uint32(wav[40:44]))
+	assertInt(t, rate/2*2*2, dataSize, "data size")
+	assertInt(t, 44+dataSize, len(wav), "file size")
+	assertInt(t, len(wav)-8, int(binary.LittleEndian.Uint32(wav[4:8])), "RIFF size")
+	assertInt(t, 2, int(binary.LittleEndian.Uint16(wav[22:24])), "channels")
+	assertInt(t, rate, int(binary.LittleEndian.Uint32(wav[24:28])), "sample rate")
+	assertInt(t, 16, int(binary.LittleEndian.Uint16(wav[34:36])), "bits per sample")
+	frameBytes := 2 * 2
+	samples := song.Samples[rate/2*frameBytes : rate*frameBytes]
+	for i, v := range wav[44:] {
+		if int8(v) != samples[i] {
+			t.Fatalf("unexpected sample byte %d", i)
+		}
+	}
+
+	if err := WriteWAV(&buffer, song, 3*time.Second, 4*time.Second); err != ErrNoSamples {
+		t.Errorf("expected ErrNoSamples for a range after the song end, got: %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package bliss
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// forceDimension describes a dimension of force vectors, and how to name it.
+type forceDimension struct {
+	name string
+	// more and less are the comparatives for positive and negative differences
+	more, less string
+	// high and low are the tags of high and low ratings
+	high, low Tag
+	value     func(forceVector ForceVector) float32
+}
+
+var forceDimensions = [...]forceDimension{
+	{"tempo", "faster", "slower", TagFast, TagSlow, func(v ForceVector) float32 { return v.Tempo }},
+	{"attack", "punchier", "softer", TagPunchy, TagSmooth, func(v ForceVector) float32 { return v.Attack }},
+	{"amplitude", "louder", "quieter", TagLoud, TagQuiet, func(v ForceVector) float32 { return v.Amplitude }},
+	{"frequency", "brighter", "darker", TagBright, TagDark, func(v ForceVector) float32 { return v.Frequency }},
+}
+
+// the rating differences, in units, from which a difference is described, and described
+// with "slightly" (under explainModerate), without adverb, or with "much" (from explainLarge).
+// A unit is 1 for Explain, and a quarter of the standard deviation of the library for Explainer.
+const (
+	explainSmall    = 1
+	explainModerate = 3
+	explainLarge    = 6
+	// explainUnits is the number of units in a standard deviation for Explainer.
+	explainUnits = 4
+)
+
+/*
+Contribution stores the contribution of a dimension of force vectors to their distance
+and cosine similarity.
+*/
+type Contribution struct {
+	/*
+		Dimension is the name of the dimension: "tempo", "attack", "amplitude" or "frequency",
+		as in ForceVector.FeatureVector.
+	*/
+	Dimension string
+	/*
+		Difference is the rating of B minus the rating of A on the dimension.
+	*/
+	Difference float32
+	/*
+		DistanceShare is the share of the dimension in the squared distance between A and B,
+		between 0 and 1. The shares of all dimensions sum to 1 (unless A and B are equal).
+	*/
+	DistanceShare float32
+	/*
+		Cosine is the contribution of the dimension to the cosine similarity of A and B:
+		the contributions of all dimensions sum to their cosine similarity.
+	*/
+	Cosine float32
+	/*
+		Descriptor describes how B differs from A on the dimension, e.g. "much brighter"
+		or "slightly slower", or is empty if the difference is negligible.
+	*/
+	Descriptor string
+}
+
+/*
+Explanation explains the distance and cosine similarity between two force vectors A and B.
+*/
+type Explanation struct {
+	/*
+		Distance is the distance between A and B, as returned by Distance.
+	*/
+	Distance float32
+	/*
+		CosineSimilarity is the cosine similarity of A and B, as returned by CosineSimilarity.
+	*/
+	CosineSimilarity float32
+	/*
+		Contributions stores the contribution of each dimension, the largest contributions
+		to the distance first.
+	*/
+	Contributions []Contribution
+	/*
+		Summary describes how B differs from A, e.g. "B is much brighter and slightly faster",
+		or "A and B are very similar". The largest differences, relative to their thresholds, come first.
+	*/
+	Summary string
+}
+
+func describeDifference(dimension *forceDimension, difference float32, unit float64) string {
+	magnitude := math.Abs(float64(difference)) / unit
+	if magnitude < explainSmall {
+		return ""
+	}
+	word := dimension.more
+	if difference < 0 {
+		word = dimension.less
+	}
+	switch {
+	case magnitude < explainModerate:
+		return "slightly " + word
+	case magnitude >= explainLarge:
+		return "much " + word
+	default:
+		return word
+	}
+}
+
+/*
+Explain explains why two force vectors a and b are (dis)similar: the contribution of each
+dimension to their distance and cosine similarity, and human-readable descriptors of how b
+differs from a, e.g. "B is much brighter and slightly faster".
+
+Descriptors are based on the absolute differences of the ratings: differences under 1
+are negligible, differences under 3 are slight, and differences from 6 are large. These
+thresholds are the same for all dimensions, although the ratings of some dimensions spread
+more than others over a library: use an Explainer fitted to a library for thresholds scaled
+to the spread of each dimension.
+*/
+func Explain(a ForceVector, b ForceVector) *Explanation {
+	return explain(a, b, [len(forceDimensions)]float64{1, 1, 1, 1})
+}
+
+/*
+Explainer explains force vectors like Explain, with thresholds scaled to the spread of each
+dimension over a library, so that e.g. "much faster" and "much brighter" are equally unusual.
+
+An Explainer can be serialized, e.g. with encoding/json.
+*/
+type Explainer struct {
+	/*
+		Deviations stores the standard deviation of each rating over the library.
+	*/
+	Deviations ForceVector
+}
+
+/*
+FitExplainer returns the Explainer of the force vectors of a library.
+
+FitExplainer panics if there are no vectors.
+*/
+func FitExplainer(forceVectors []ForceVector) *Explainer {
+	if len(forceVectors) == 0 {
+		panic("bliss: no force vectors to fit")
+	}
+	n := float64(len(forceVectors))
+	var mean, deviations [len(forceDimensions)]float64
+	for _, forceVector := range forceVectors {
+		for d, x := range forceValues(forceVector) {
+			mean[d] += x / n
+		}
+	}
+	for _, forceVector := range forceVectors {
+		for d, x := range forceValues(forceVector) {
+			deviations[d] += (x - mean[d]) * (x - mean[d]) / n
+		}
+	}
+	for d := range deviations {
+		deviations[d] = math.Sqrt(deviations[d])
+	}
+	return &Explainer{
+		Deviations: newForceVectorOf(deviations[:]),
+	}
+}
+
+/*
+Explain is like the Explain function, with thresholds in standard deviations of each
+dimension over the library: differences under 0.25 deviations are negligible, differences
+under 0.75 deviations are slight, and differences from 1.5 deviations are large. Dimensions
+with a zero deviation use the absolute thresholds of the Explain function.
+*/
+func (explainer *Explainer) Explain(a ForceVector, b ForceVector) *Explanation {
+	units := forceValues(explainer.Deviations)
+	for d := range units {
+		units[d] /= explainUnits
+		if units[d] <= 0 {
+			units[d] = 1
+		}
+	}
+	return explain(a, b, units)
+}
+
+func explain(a ForceVector, b ForceVector, units [len(forceDimensions)]float64) *Explanation {
+	explanation := &Explanation{
+		Distance:         Distance(a, b),
+		CosineSimilarity: CosineSimilarity(a, b),
+		Contributions:    make([]Contribution, len(forceDimensions)),
+	}
+	var squared, normA, normB float64
+	for i := range forceDimensions {
+		x, y := float64(forceDimensions[i].value(a)), float64(forceDimensions[i].value(b))
+		squared += (y - x) * (y - x)
+		normA += x * x
+		normB += y * y
+	}
+	for i := range forceDimensions {
+		dimension := &forceDimensions[i]
+		x, y := dimension.value(a), dimension.value(b)
+		contribution := Contribution{
+			Dimension:  dimension.name,
+			Difference: y - x,
+			Descriptor: describeDifference(dimension, y-x, units[i]),
+		}
+		if squared > 0 {
+			contribution.DistanceShare = float32(float64(y-x) * float64(y-x) / squared)
+		}
+		if normA > 0 && normB > 0 {
+			contribution.Cosine = float32(float64(x) * float64(y) / math.Sqrt(normA*normB))
+		}
+		explanation.Contributions[i] = contribution
+	}
+
+	// the descriptors, the most unusual differences first
+	order := make([]int, len(forceDimensions))
+	for i := range order {
+		order[i] = i
+	}
+	magnitude := func(i int) float64 {
+		return math.Abs(float64(explanation.Contributions[i].Difference)) / units[i]
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return magnitude(order[i]) > magnitude(order[j])
+	})
+	var descriptors []string
+	for _, i := range order {
+		if descriptor := explanation.Contributions[i].Descriptor; descriptor != "" {
+			descriptors = append(descriptors, descriptor)
+		}
+	}
+
+	sort.SliceStable(explanation.Contributions, func(i, j int) bool {
+		return explanation.Contributions[i].DistanceShare > explanation.Contributions[j].DistanceShare
+	})
+	switch len(descriptors) {
+	case 0:
+		explanation.Summary = "A and B are very similar"
+	case 1:
+		explanation.Summary = "B is " + descriptors[0]
+	default:
+		last := len(descriptors) - 1
+		explanation.Summary = "B is " + strings.Join(descriptors[:last], ", ") + " and " + descriptors[last]
+	}
+	return explanation
+}
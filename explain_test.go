@@ -0,0 +1,41 @@
+package bliss
+
+import (
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	a := ForceVector{Tempo: -8, Attack: -15, Amplitude: -15, Frequency: -10}
+	b := ForceVector{Tempo: -6, Attack: -15.5, Amplitude: -14, Frequency: -2}
+	explanation := Explain(a, b)
+	assertString(t, "B is much brighter, slightly faster and slightly louder", explanation.Summary, "summary")
+	assertString(t, "frequency", explanation.Contributions[0].Dimension, "largest contribution")
+	assertFloat(t, 8, explanation.Contributions[0].Difference, "frequency difference")
+	assertString(t, "", explanation.Contributions[3].Descriptor, "negligible difference descriptor")
+
+	var share, cosine float32
+	for _, contribution := range explanation.Contributions {
+		share += contribution.DistanceShare
+		cosine += contribution.Cosine
+	}
+	assertFloat(t, 1, share, "distance shares sum")
+	assertFloat(t, explanation.CosineSimilarity, cosine, "cosine contributions sum")
+
+	assertString(t, "A and B are very similar", Explain(a, a).Summary, "same vectors summary")
+	assertString(t, "B is darker", Explain(a, ForceVector{Tempo: -8, Attack: -15, Amplitude: -15, Frequency: -14}).Summary, "single descriptor summary")
+}
+
+func TestExplainer(t *testing.T) {
+	// tempos spread much more than frequencies over the library
+	explainer := FitExplainer([]ForceVector{
+		{Tempo: -20, Frequency: -1},
+		{Tempo: 20, Frequency: 1},
+	})
+	assertFloat(t, 20, explainer.Deviations.Tempo, "tempo deviation")
+	assertFloat(t, 1, explainer.Deviations.Frequency, "frequency deviation")
+	assertFloat(t, 0, explainer.Deviations.Attack, "attack deviation")
+
+	explanation := explainer.Explain(ForceVector{}, ForceVector{Tempo: 4, Attack: 2, Frequency: 2})
+	assertString(t, "B is much brighter and slightly punchier", explanation.Summary, "scaled summary")
+	assertString(t, "B is much faster and slightly brighter", Explain(ForceVector{}, ForceVector{Tempo: 8, Frequency: 2}).Summary, "absolute summary")
+}
@@ -0,0 +1,457 @@
+package bliss
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	// featureFrameSize and featureHop are the DFT size and hop used for spectral features.
+	featureFrameSize = 2048
+	featureHop       = 1024
+	// melFilters is the number of mel filters used for MFCCs.
+	melFilters = 26
+	// mfccCoefficients is the number of MFCCs.
+	mfccCoefficients = 13
+	// rolloffRatio is the ratio of the spectral energy under the rolloff frequency.
+	rolloffRatio = 0.85
+	// chromaMinFrequency and chromaMaxFrequency bound the frequencies used for chroma, in Hz.
+	chromaMinFrequency = 55
+	chromaMaxFrequency = 5000
+)
+
+// pitchClassNames are the names of the 12 pitch classes, from C.
+var pitchClassNames = [12]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+/*
+FeatureVector is a vector of named dimensions, for example the ratings of a ForceVector
+(see ForceVector.FeatureVector) or the features returned by ExtractFeatures.
+
+Two feature vectors are comparable if they have the same Names, in the same order.
+*/
+type FeatureVector struct {
+	/*
+		Names are the names of the dimensions of the vector.
+	*/
+	Names []string
+	/*
+		Values are the values of the dimensions of the vector, in the same order as Names.
+	*/
+	Values []float64
+}
+
+/*
+FeatureVector returns the ratings of the force vector as a FeatureVector, with the
+dimensions "tempo", "attack", "amplitude" and "frequency".
+*/
+func (forceVector ForceVector) FeatureVector() FeatureVector {
+	return FeatureVector{
+		Names: []string{"tempo", "attack", "amplitude", "frequency"},
+		Values: []float64{
+			float64(forceVector.Tempo),
+			float64(forceVector.Attack),
+			float64(forceVector.Amplitude),
+			float64(forceVector.Frequency),
+		},
+	}
+}
+
+/*
+Value returns the value of the dimension name of the vector, and whether it exists.
+*/
+func (featureVector FeatureVector) Value(name string) (float64, bool) {
+	for i, n := range featureVector.Names {
+		if n == name {
+			return featureVector.Values[i], true
+		}
+	}
+	return 0, false
+}
+
+func checkComparable(a FeatureVector, b FeatureVector) {
+	if len(a.Names) != len(b.Names) {
+		panic("bliss: feature vectors have different dimensions")
+	}
+	for i := range a.Names {
+		if a.Names[i] != b.Names[i] {
+			panic("bliss: feature vectors have different dimensions")
+		}
+	}
+}
+
+/*
+FeatureDistance computes the euclidean distance between two feature vectors, like
+Distance does for force vectors.
+
+FeatureDistance panics if the vectors have different Names.
+*/
+func FeatureDistance(a FeatureVector, b FeatureVector) float64 {
+	checkComparable(a, b)
+	var sum float64
+	for i := range a.Values {
+		d := a.Values[i] - b.Values[i]
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
+
+/*
+FeatureCosineSimilarity computes the cosine similarity between two feature vectors,
+like CosineSimilarity does for force vectors.
+
+FeatureCosineSimilarity panics if the vectors have different Names.
+*/
+func FeatureCosineSimilarity(a FeatureVector, b FeatureVector) float64 {
+	checkComparable(a, b)
+	var dot, normA, normB float64
+	for i := range a.Values {
+		dot += a.Values[i] * b.Values[i]
+		normA += a.Values[i] * a.Values[i]
+		normB += b.Values[i] * b.Values[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+/*
+Normalizer standardizes feature vectors, so that each dimension has a mean of 0 and
+a standard deviation of 1 over a set of vectors, and that dimensions of different scales
+weigh the same in distances.
+
+A Normalizer can be serialized, e.g. with encoding/json, to reuse it at query time.
+*/
+type Normalizer struct {
+	/*
+		Names are the names of the dimensions of the normalized vectors.
+	*/
+	Names []string
+	/*
+		Means are the means of each dimension.
+	*/
+	Means []float64
+	/*
+		Deviations are the standard deviations of each dimension.
+	*/
+	Deviations []float64
+}
+
+/*
+FitNormalizer returns the Normalizer of a set of feature vectors.
+
+FitNormalizer panics if the vectors have different Names, or if there are no vectors.
+*/
+func FitNormalizer(featureVectors []FeatureVector) *Normalizer {
+	if len(featureVectors) == 0 {
+		panic("bliss: no feature vectors to fit")
+	}
+	n := len(featureVectors[0].Values)
+	normalizer := &Normalizer{
+		Names:      featureVectors[0].Names,
+		Means:      make([]float64, n),
+		Deviations: make([]float64, n),
+	}
+	for _, featureVector := range featureVectors {
+		checkComparable(featureVectors[0], featureVector)
+		for i, v := range featureVector.Values {
+			normalizer.Means[i] += v
+		}
+	}
+	for i := range normalizer.Means {
+		normalizer.Means[i] /= float64(len(featureVectors))
+	}
+	for _, featureVector := range featureVectors {
+		for i, v := range featureVector.Values {
+			d := v - normalizer.Means[i]
+			normalizer.Deviations[i] += d * d
+		}
+	}
+	for i := range normalizer.Deviations {
+		normalizer.Deviations[i] = math.Sqrt(normalizer.Deviations[i] / float64(len(featureVectors)))
+	}
+	return normalizer
+}
+
+/*
+Normalize returns the standardized feature vector. Dimensions with a standard deviation
+of zero are only centered.
+
+Normalize panics if the vector has different Names than the Normalizer.
+*/
+func (normalizer *Normalizer) Normalize(featureVector FeatureVector) FeatureVector {
+	checkComparable(FeatureVector{Names: normalizer.Names}, featureVector)
+	normalized := FeatureVector{
+		Names:  featureVector.Names,
+		Values: make([]float64, len(featureVector.Values)),
+	}
+	for i, v := range featureVector.Values {
+		normalized.Values[i] = v - normalizer.Means[i]
+		if normalizer.Deviations[i] > 0 {
+			normalized.Values[i] /= normalizer.Deviations[i]
+		}
+	}
+	return normalized
+}
+
+// quantile returns the q-quantile (0 <= q <= 1) of sorted values, interpolating linearly
+// between the closest values.
+func quantile(sorted []float64, q float64) float64 {
+	position := q * float64(len(sorted)-1)
+	i := int(position)
+	if i >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (position-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+// frameFeatures are the features of a single DFT frame.
+type frameFeatures struct {
+	centroid float64
+	rolloff  float64
+	flatness float64
+	// zeroCrossings is the zero crossing rate, per second
+	zeroCrossings float64
+	mfcc          [mfccCoefficients]float64
+	// chroma is the energy of each pitch class, summing to 1 (or 0 for silent frames)
+	chroma [12]float64
+}
+
+func melOf(frequency float64) float64 {
+	return 2595 * math.Log10(1+frequency/700)
+}
+
+func frequencyOfMel(mel float64) float64 {
+	return 700 * (math.Pow(10, mel/2595) - 1)
+}
+
+// melFilterbank returns the triangular mel filters of a power spectrum of bins bins.
+func melFilterbank(bins int, sampleRate int) [melFilters][]float64 {
+	var filterbank [melFilters][]float64
+	resolution := float64(sampleRate) / float64(2*(bins-1))
+	maxMel := melOf(float64(sampleRate) / 2)
+	var centers [melFilters + 2]float64
+	for i := range centers {
+		centers[i] = frequencyOfMel(maxMel * float64(i) / float64(melFilters+1))
+	}
+	for m := range filterbank {
+		filterbank[m] = make([]float64, bins)
+		low, center, high := centers[m], centers[m+1], centers[m+2]
+		for k := range filterbank[m] {
+			f := float64(k) * resolution
+			switch {
+			case f > low && f <= center:
+				filterbank[m][k] = (f - low) / (center - low)
+			case f > center && f < high:
+				filterbank[m][k] = (high - f) / (high - center)
+			}
+		}
+	}
+	return filterbank
+}
+
+// pitchClasses returns the pitch class of each bin of a power spectrum, or -1 for
+// bins outside the chroma frequencies.
+func pitchClasses(bins int, sampleRate int) []int {
+	classes := make([]int, bins)
+	resolution := float64(sampleRate) / float64(2*(bins-1))
+	for k := range classes {
+		f := float64(k) * resolution
+		if f < chromaMinFrequency || f > chromaMaxFrequency {
+			classes[k] = -1
+			continue
+		}
+		// MIDI note number, A4 (440 Hz) being 69
+		note := int(math.Round(12*math.Log2(f/440))) + 69
+		classes[k] = note % 12
+	}
+	return classes
+}
+
+func computeFrameFeatures(mono []float64, sampleRate int) []frameFeatures {
+	bins := featureFrameSize/2 + 1
+	resolution := float64(sampleRate) / featureFrameSize
+	filterbank := melFilterbank(bins, sampleRate)
+	classes := pitchClasses(bins, sampleRate)
+
+	var features []frameFeatures
+	forEachSpectrum(mono, featureFrameSize, featureHop, func(frame int, power []float64) {
+		var f frameFeatures
+
+		var total, weighted, logSum float64
+		for k, p := range power {
+			total += p
+			weighted += p * float64(k) * resolution
+			logSum += math.Log(p + 1e-12)
+		}
+		if total > 0 {
+			f.centroid = weighted / total
+			f.flatness = math.Exp(logSum/float64(len(power))) / (total / float64(len(power)))
+			var cumulated float64
+			for k, p := range power {
+				cumulated += p
+				if cumulated >= rolloffRatio*total {
+					f.rolloff = float64(k) * resolution
+					break
+				}
+			}
+		}
+
+		start := frame * featureHop
+		end := start + featureFrameSize
+		if end > len(mono) {
+			end = len(mono)
+		}
+		var crossings int
+		for i := start + 1; i < end; i++ {
+			if (mono[i-1] < 0) != (mono[i] < 0) {
+				crossings++
+			}
+		}
+		if end > start {
+			f.zeroCrossings = float64(crossings) * float64(sampleRate) / float64(end-start)
+		}
+
+		var energies [melFilters]float64
+		for m, filter := range filterbank {
+			for k, w := range filter {
+				energies[m] += w * power[k]
+			}
+			energies[m] = math.Log(energies[m] + 1e-12)
+		}
+		// DCT-II of the log mel energies
+		for c := range f.mfcc {
+			for m, e := range energies {
+				f.mfcc[c] += e * math.Cos(math.Pi*float64(c)*(float64(m)+0.5)/melFilters)
+			}
+		}
+
+		var chromaTotal float64
+		for k, class := range classes {
+			if class >= 0 {
+				f.chroma[class] += power[k]
+				chromaTotal += power[k]
+			}
+		}
+		if chromaTotal > 0 {
+			for i := range f.chroma {
+				f.chroma[i] /= chromaTotal
+			}
+		}
+
+		features = append(features, f)
+	})
+	return features
+}
+
+// frameSteps returns the number of steps of the samples, and the step of each of the
+// frames of computeFrameFeatures: the step containing the center of the frame.
+func frameSteps(frames int, samples int, sampleRate int, step time.Duration) (int, []int) {
+	stepLength := step.Seconds() * float64(sampleRate)
+	steps := int(math.Ceil(float64(samples) / stepLength))
+	indices := make([]int, frames)
+	for i := range indices {
+		indices[i] = int((float64(i*featureHop) + featureFrameSize/2) / stepLength)
+		if indices[i] >= steps {
+			indices[i] = steps - 1
+		}
+	}
+	return steps, indices
+}
+
+// normalize scales v so that its norm is 1, unless it is 0.
+func normalize(v []float64) {
+	var norm float64
+	for _, x := range v {
+		norm += x * x
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range v {
+			v[i] /= norm
+		}
+	}
+}
+
+// chromagram returns the mean chroma of the frames of each step of the song, normalized
+// so that the chroma of each step has a norm of 1 (or 0 if the step is silent).
+func chromagram(mono []float64, sampleRate int, step time.Duration) [][12]float64 {
+	features := computeFrameFeatures(mono, sampleRate)
+	steps, indices := frameSteps(len(features), len(mono), sampleRate, step)
+	chroma := make([][12]float64, steps)
+	for i, f := range features {
+		for c, v := range f.chroma {
+			chroma[indices[i]][c] += v
+		}
+	}
+	for i := range chroma {
+		normalize(chroma[i][:])
+	}
+	return chroma
+}
+
+// chromaSimilarity returns the cosine similarity of two normalized chroma vectors.
+func chromaSimilarity(a *[12]float64, b *[12]float64) float64 {
+	var dot float64
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return dot
+}
+
+/*
+ExtractFeatures computes an extended set of features of a Song, which are finer than its
+ForceVector, from the DFT of frames of 2048 samples.
+
+The returned FeatureVector has the following dimensions, averaged over all frames:
+"spectral_centroid" (Hz), "spectral_rolloff" (Hz, under which 85% of the energy lies),
+"spectral_flatness" (between 0 for a pure tone and 1 for white noise),
+"zero_crossing_rate" (per second), "mfcc_mean_0" to "mfcc_mean_12" and "mfcc_variance_0"
+to "mfcc_variance_12" (the means and variances of the 13 mel-frequency cepstral coefficients),
+and "chroma_C" to "chroma_B" (the share of energy of each of the 12 pitch classes).
+
+Features have very different scales: use a Normalizer before computing distances.
+
+If the Song has no samples, ExtractFeatures returns ErrNoSamples.
+*/
+func ExtractFeatures(song *Song) (FeatureVector, error) {
+	mono, err := song.monoSamples()
+	if err != nil {
+		return FeatureVector{}, err
+	}
+	frames := computeFrameFeatures(mono, song.SampleRate)
+	n := float64(len(frames))
+
+	var featureVector FeatureVector
+	add := func(name string, value float64) {
+		featureVector.Names = append(featureVector.Names, name)
+		featureVector.Values = append(featureVector.Values, value)
+	}
+	mean := func(value func(f *frameFeatures) float64) float64 {
+		var sum float64
+		for i := range frames {
+			sum += value(&frames[i])
+		}
+		return sum / n
+	}
+	add("spectral_centroid", mean(func(f *frameFeatures) float64 { return f.centroid }))
+	add("spectral_rolloff", mean(func(f *frameFeatures) float64 { return f.rolloff }))
+	add("spectral_flatness", mean(func(f *frameFeatures) float64 { return f.flatness }))
+	add("zero_crossing_rate", mean(func(f *frameFeatures) float64 { return f.zeroCrossings }))
+	var means [mfccCoefficients]float64
+	for c := range means {
+		means[c] = mean(func(f *frameFeatures) float64 { return f.mfcc[c] })
+		add("mfcc_mean_"+strconv.Itoa(c), means[c])
+	}
+	for c := range means {
+		add("mfcc_variance_"+strconv.Itoa(c), mean(func(f *frameFeatures) float64 {
+			d := f.mfcc[c] - means[c]
+			return d * d
+		}))
+	}
+	for i, name := range pitchClassNames {
+		add("chroma_"+name, mean(func(f *frameFeatures) float64 { return f.chroma[i] }))
+	}
+	return featureVector, nil
+}
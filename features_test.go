@@ -0,0 +1,58 @@
+package bliss
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestExtractFeatures(t *testing.T) {
+	const rate = 22050
+	tone, err := ExtractFeatures(newTestSong(rate, 1, sine(rate, 5*rate, 440, 0.5)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, 4+2*mfccCoefficients+12, len(tone.Values), "features count")
+	centroid, _ := tone.Value("spectral_centroid")
+	assertNear(t, 440, centroid, 20, "tone spectral centroid")
+	zeroCrossings, _ := tone.Value("zero_crossing_rate")
+	assertNear(t, 880, zeroCrossings, 10, "tone zero crossing rate")
+	chroma, _ := tone.Value("chroma_A")
+	if chroma < 0.9 {
+		t.Errorf("expected the tone energy in the A pitch class, got: %f", chroma)
+	}
+
+	random := rand.New(rand.NewSource(1))
+	noise := make([]float64, 5*rate)
+	for i := range noise {
+		noise[i] = random.Float64() - 0.5
+	}
+	white, err := ExtractFeatures(newTestSong(rate, 1, noise))
+	if err != nil {
+		t.Fatal(err)
+	}
+	toneFlatness, _ := tone.Value("spectral_flatness")
+	noiseFlatness, _ := white.Value("spectral_flatness")
+	if toneFlatness > 0.1 || noiseFlatness < 0.3 {
+		t.Errorf("unexpected flatness: tone: %f, noise: %f", toneFlatness, noiseFlatness)
+	}
+
+	normalizer := FitNormalizer([]FeatureVector{tone, white})
+	a, b := normalizer.Normalize(tone), normalizer.Normalize(white)
+	for i := range a.Values {
+		if a.Values[i] != 0 || b.Values[i] != 0 {
+			assertNear(t, -a.Values[i], b.Values[i], 1e-9, "normalized "+a.Names[i])
+		}
+	}
+	assertNear(t, 0, FeatureDistance(a, a), 0, "distance to itself")
+	assertNear(t, -1, FeatureCosineSimilarity(a, b), 1e-9, "opposite cosine similarity")
+}
+
+func TestForceFeatureVector(t *testing.T) {
+	forceVector := ForceVector{Tempo: 1, Attack: 2, Amplitude: 3, Frequency: 4}
+	amplitude, ok := forceVector.FeatureVector().Value("amplitude")
+	if !ok {
+		t.Fatal("expected an amplitude dimension")
+	}
+	assertNear(t, 3, amplitude, 0, "amplitude dimension")
+	assertNear(t, float64(Distance(forceVector, ForceVector{})), FeatureDistance(forceVector.FeatureVector(), ForceVector{}.FeatureVector()), 1e-5, "distance")
+}
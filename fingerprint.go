@@ -0,0 +1,150 @@
+package bliss
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+const (
+	// fingerprintFrame is the maximum duration of the DFT frames of fingerprints.
+	fingerprintFrame = 0.05
+	// fingerprintHop is the duration between two fingerprint frames.
+	fingerprintHop = 25 * time.Millisecond
+	// fingerprintSmoothing is the number of DFT frames summed in each fingerprint frame,
+	// so that fingerprints are robust to small time shifts.
+	fingerprintSmoothing = 8
+	// fingerprintLow and fingerprintHigh bound the frequency bands of fingerprints, in Hz.
+	fingerprintLow  = 300
+	fingerprintHigh = 2000
+	// fingerprintBands is the number of frequency bands of fingerprints: one more than
+	// the number of bits of each frame.
+	fingerprintBands = 33
+)
+
+/*
+Fingerprint is a compact fingerprint of the audio of a Song: it is robust to encoding,
+resampling and volume changes, so that copies of the same recording have close fingerprints.
+
+Each frame of the fingerprint stores 32 bits, the signs of the variations of the energy
+of 33 frequency bands between 300 and 2000 Hz over 200ms, with 40 frames per second.
+
+A Fingerprint can be serialized, e.g. with encoding/json, to be compared with songs
+analyzed later, without decoding the song again.
+*/
+type Fingerprint struct {
+	/*
+		Frames stores the bits of each frame of the fingerprint.
+	*/
+	Frames []uint32
+}
+
+/*
+ComputeFingerprint computes the Fingerprint of a Song.
+
+If the Song has no samples, ComputeFingerprint returns ErrNoSamples.
+*/
+func ComputeFingerprint(song *Song) (*Fingerprint, error) {
+	mono, err := song.monoSamples()
+	if err != nil {
+		return nil, err
+	}
+	// the largest power of two not longer than fingerprintFrame
+	size := nextPowerOfTwo(int(fingerprintFrame*float64(song.SampleRate))+1) / 2
+	if size < 2 {
+		size = 2
+	}
+	hop := int(fingerprintHop.Seconds() * float64(song.SampleRate))
+	if hop < 1 {
+		hop = 1
+	}
+	resolution := float64(song.SampleRate) / float64(size)
+	var edges [fingerprintBands + 1]int
+	for i := range edges {
+		frequency := fingerprintLow * math.Pow(fingerprintHigh/fingerprintLow, float64(i)/fingerprintBands)
+		edges[i] = int(math.Round(frequency / resolution))
+	}
+
+	fingerprint := &Fingerprint{}
+	// the band energies of the last DFT frames
+	var history [fingerprintSmoothing][fingerprintBands]float64
+	var previous [fingerprintBands]float64
+	forEachSpectrum(mono, size, hop, func(frame int, power []float64) {
+		bands := &history[frame%fingerprintSmoothing]
+		for b := range bands {
+			bands[b] = 0
+			// bands have at least one bin
+			to := edges[b+1]
+			if to <= edges[b] {
+				to = edges[b] + 1
+			}
+			for k := edges[b]; k < to && k < len(power); k++ {
+				bands[b] += power[k]
+			}
+		}
+		if frame < fingerprintSmoothing-1 {
+			return
+		}
+		var energies [fingerprintBands]float64
+		for _, h := range history {
+			for b, e := range h {
+				energies[b] += e
+			}
+		}
+		if frame >= fingerprintSmoothing {
+			var frameBits uint32
+			for b := 0; b < fingerprintBands-1; b++ {
+				if energies[b]-energies[b+1]-(previous[b]-previous[b+1]) > 0 {
+					frameBits |= 1 << uint(b)
+				}
+			}
+			fingerprint.Frames = append(fingerprint.Frames, frameBits)
+		}
+		previous = energies
+	})
+	return fingerprint, nil
+}
+
+/*
+FingerprintSimilarity compares two fingerprints, returning their similarity between
+0 (unrelated audio) and 1 (same audio), and the offset of b relative to a at which they
+match best. A positive offset means that the audio of b starts later than the audio of a,
+e.g. because of a longer leading silence.
+
+Offsets up to maxOffset are searched, and at least half of the shorter fingerprint must
+overlap.
+*/
+func FingerprintSimilarity(a *Fingerprint, b *Fingerprint, maxOffset time.Duration) (float64, time.Duration) {
+	shorter := len(a.Frames)
+	if len(b.Frames) < shorter {
+		shorter = len(b.Frames)
+	}
+	minOverlap := (shorter + 1) / 2
+	if minOverlap == 0 {
+		return 0, 0
+	}
+	maxFrames := int(maxOffset / fingerprintHop)
+	bestError, bestOffset := 1.0, 0
+	for offset := -maxFrames; offset <= maxFrames; offset++ {
+		// frame i of a matches frame i+offset of b
+		from, to := 0, len(a.Frames)
+		if from < -offset {
+			from = -offset
+		}
+		if to > len(b.Frames)-offset {
+			to = len(b.Frames) - offset
+		}
+		if to-from < minOverlap {
+			continue
+		}
+		differences := 0
+		for i := from; i < to; i++ {
+			differences += bits.OnesCount32(a.Frames[i] ^ b.Frames[i+offset])
+		}
+		if e := float64(differences) / float64(32*(to-from)); e < bestError {
+			bestError, bestOffset = e, offset
+		}
+	}
+	// unrelated fingerprints differ by half of their bits
+	return math.Max(0, 1-2*bestError), time.Duration(bestOffset) * fingerprintHop
+}
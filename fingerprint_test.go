@@ -0,0 +1,59 @@
+package bliss
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// melody returns samples of random decaying notes of half a second, drawn from the seed.
+func melody(sampleRate int, seconds int, seed int64) []float64 {
+	random := rand.New(rand.NewSource(seed))
+	var samples []float64
+	for i := 0; i < 2*seconds; i++ {
+		note := sine(sampleRate, sampleRate/2, 220*float64(int(1)<<uint(random.Intn(3)))*(1+float64(random.Intn(12))/12), 0.3)
+		for j, v := range sine(sampleRate, sampleRate/2, 300+random.Float64()*1700, 0.2) {
+			note[j] = (note[j] + v) * math.Exp(-4*float64(j)/float64(sampleRate))
+		}
+		samples = append(samples, note...)
+	}
+	return samples
+}
+
+func TestFingerprintSimilarity(t *testing.T) {
+	const rate = 22050
+	original := melody(rate, 30, 1)
+	a, err := ComputeFingerprint(newTestSong(rate, 1, original))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNear(t, 1200, float64(len(a.Frames)), 10, "frames count")
+
+	// the same recording, quieter, with a leading silence and a different sampling rate
+	copied := make([]float64, 2*rate+1000)
+	for _, v := range original {
+		copied = append(copied, 0.5*v)
+	}
+	resampled := make([]float64, len(copied)*2)
+	for i := range resampled {
+		resampled[i] = copied[i/2]
+	}
+	b, err := ComputeFingerprint(newTestSong(2*rate, 1, resampled))
+	if err != nil {
+		t.Fatal(err)
+	}
+	similarity, offset := FingerprintSimilarity(a, b, 5*time.Second)
+	if similarity < 0.7 {
+		t.Errorf("expected a high similarity for the same recording, got: %f", similarity)
+	}
+	assertNear(t, 2, offset.Seconds(), 0.1, "offset")
+
+	c, err := ComputeFingerprint(newTestSong(rate, 1, melody(rate, 30, 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if similarity, _ := FingerprintSimilarity(a, c, 5*time.Second); similarity > 0.3 {
+		t.Errorf("expected a low similarity for different recordings, got: %f", similarity)
+	}
+}
@@ -0,0 +1,163 @@
+package bliss
+
+import (
+	"math"
+	"strconv"
+)
+
+/*
+Mode is the mode of a musical key.
+*/
+type Mode int
+
+const (
+	/*
+		Major is the major mode.
+	*/
+	Major Mode = 0
+	/*
+		Minor is the minor mode.
+	*/
+	Minor Mode = 1
+)
+
+func (mode Mode) String() string {
+	if mode == Minor {
+		return "minor"
+	}
+	return "major"
+}
+
+// Krumhansl-Kessler key profiles, from the tonic.
+var (
+	majorProfile = [12]float64{6.35, 2.23, 3.48, 2.33, 4.38, 4.09, 2.52, 5.19, 2.39, 3.66, 2.29, 2.88}
+	minorProfile = [12]float64{6.33, 2.68, 3.52, 5.38, 2.60, 3.53, 2.54, 4.75, 3.98, 2.69, 3.34, 3.17}
+)
+
+/*
+Key is the musical key of a Song.
+*/
+type Key struct {
+	/*
+		Tonic is the pitch class of the tonic of the key, from 0 (C) to 11 (B).
+	*/
+	Tonic int
+	/*
+		Mode is the mode of the key, Major or Minor.
+	*/
+	Mode Mode
+	/*
+		Confidence is the correlation between the song pitch classes and the key
+		profile, between 0 and 1. Songs without a clear tonality have a low confidence.
+	*/
+	Confidence float64
+}
+
+/*
+Name returns the name of the key, e.g. "C# minor".
+*/
+func (key Key) Name() string {
+	return pitchClassNames[key.Tonic] + " " + key.Mode.String()
+}
+
+func (key Key) String() string {
+	return key.Name()
+}
+
+/*
+Camelot returns the Camelot wheel code of the key, e.g. "8B" for C major and "8A"
+for A minor.
+*/
+func (key Key) Camelot() string {
+	letter := "B"
+	if key.Mode == Minor {
+		letter = "A"
+	}
+	return strconv.Itoa(key.camelotNumber()+1) + letter
+}
+
+// camelotNumber returns the Camelot wheel number of the key, minus one.
+func (key Key) camelotNumber() int {
+	tonic := key.Tonic
+	if key.Mode == Minor {
+		// the relative major
+		tonic = (tonic + 3) % 12
+	}
+	// each step on the wheel is a fifth (7 semitones), C major being 8B
+	return (7*tonic + 7) % 12
+}
+
+/*
+Compatible reports whether two keys are compatible for harmonic mixing: they are
+the same or adjacent on the Camelot wheel (same mode and a fifth apart, or relative keys).
+*/
+func (key Key) Compatible(other Key) bool {
+	a, b := key.camelotNumber(), other.camelotNumber()
+	if key.Mode != other.Mode {
+		return a == b
+	}
+	d := (a - b + 12) % 12
+	return d == 0 || d == 1 || d == 11
+}
+
+func correlation(x []float64, y []float64) float64 {
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= float64(len(x))
+	meanY /= float64(len(y))
+	var xy, xx, yy float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		xy += dx * dy
+		xx += dx * dx
+		yy += dy * dy
+	}
+	if xx == 0 || yy == 0 {
+		return 0
+	}
+	return xy / math.Sqrt(xx*yy)
+}
+
+/*
+DetectKey estimates the musical key of a Song, by correlating the energy of its pitch
+classes (the chroma features of ExtractFeatures) with the Krumhansl-Kessler key profiles.
+
+If the Song has no samples, DetectKey returns ErrNoSamples.
+*/
+func DetectKey(song *Song) (*Key, error) {
+	mono, err := song.monoSamples()
+	if err != nil {
+		return nil, err
+	}
+	var chroma [12]float64
+	for _, frame := range computeFrameFeatures(mono, song.SampleRate) {
+		for i, v := range frame.chroma {
+			chroma[i] += v
+		}
+	}
+	return keyOf(chroma), nil
+}
+
+func keyOf(chroma [12]float64) *Key {
+	key := &Key{
+		Confidence: -1,
+	}
+	for tonic := 0; tonic < 12; tonic++ {
+		for mode, profile := range [...]*[12]float64{&majorProfile, &minorProfile} {
+			var rotated [12]float64
+			for i := range rotated {
+				rotated[i] = profile[(i-tonic+12)%12]
+			}
+			if r := correlation(chroma[:], rotated[:]); r > key.Confidence {
+				key.Tonic = tonic
+				key.Mode = Mode(mode)
+				key.Confidence = r
+			}
+		}
+	}
+	key.Confidence = math.Max(0, key.Confidence)
+	return key
+}
@@ -0,0 +1,47 @@
+package bliss
+
+import (
+	"testing"
+)
+
+func TestCamelot(t *testing.T) {
+	for _, test := range []struct {
+		key     Key
+		camelot string
+	}{
+		{Key{Tonic: 0, Mode: Major}, "8B"},
+		{Key{Tonic: 9, Mode: Minor}, "8A"},
+		{Key{Tonic: 6, Mode: Major}, "2B"},
+		{Key{Tonic: 4, Mode: Minor}, "9A"},
+		{Key{Tonic: 4, Mode: Major}, "12B"},
+		{Key{Tonic: 11, Mode: Major}, "1B"},
+	} {
+		assertString(t, test.camelot, test.key.Camelot(), test.key.Name()+" camelot code")
+	}
+	cMajor := Key{Tonic: 0, Mode: Major}
+	if !cMajor.Compatible(Key{Tonic: 7, Mode: Major}) || !cMajor.Compatible(Key{Tonic: 9, Mode: Minor}) {
+		t.Error("expected C major to be compatible with G major and A minor")
+	}
+	if cMajor.Compatible(Key{Tonic: 2, Mode: Major}) || cMajor.Compatible(Key{Tonic: 4, Mode: Minor}) {
+		t.Error("expected C major not to be compatible with D major and E minor")
+	}
+}
+
+func TestDetectKey(t *testing.T) {
+	const rate = 22050
+	// a C major triad: C4, E4, G4
+	samples := make([]float64, 5*rate)
+	for _, frequency := range []float64{261.63, 329.63, 392.00} {
+		for i, v := range sine(rate, len(samples), frequency, 0.2) {
+			samples[i] += v
+		}
+	}
+	key, err := DetectKey(newTestSong(rate, 1, samples))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "C major", key.Name(), "key")
+	if key.Confidence < 0.5 {
+		t.Errorf("expected a high confidence, got: %f", key.Confidence)
+	}
+}
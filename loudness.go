@@ -0,0 +1,306 @@
+package bliss
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+const (
+	// absoluteGate is the absolute gating threshold of BS.1770, in LUFS.
+	absoluteGate = -70
+	// relativeGate is the relative gating threshold of BS.1770, in LU.
+	relativeGate = -10
+	// rangeGate is the relative gating threshold of EBU Tech 3342 (loudness range), in LU.
+	rangeGate = -20
+	// replayGainReference is the ReplayGain 2.0 reference loudness, in LUFS.
+	replayGainReference = -18
+	// truePeakTaps is the length of each phase of the true peak interpolation filter.
+	truePeakTaps = 12
+)
+
+/*
+Loudness stores the loudness measurements of a Song, per EBU R128 / ITU-R BS.1770.
+
+The measurements of a silent song are infinite (see each field), and cannot be encoded
+as JSON as is.
+*/
+type Loudness struct {
+	/*
+		Integrated is the integrated (gated) loudness of the song, in LUFS.
+		It is -Inf for a silent song.
+	*/
+	Integrated float64
+	/*
+		Range is the loudness range of the song (EBU Tech 3342), in LU.
+	*/
+	Range float64
+	/*
+		SamplePeak is the highest absolute sample value, in dBFS.
+		It is -Inf for a silent song.
+	*/
+	SamplePeak float64
+	/*
+		TruePeak is the highest absolute value of the 4x oversampled signal, in dBTP.
+		It is -Inf for a silent song.
+	*/
+	TruePeak float64
+	/*
+		TrackGain is the ReplayGain 2.0 track gain, in dB: the gain to apply to reach
+		a loudness of -18 LUFS. It is +Inf for a silent song.
+	*/
+	TrackGain float64
+	// blocks are the mean square powers of the 400ms gating blocks, for AlbumGain.
+	blocks []float64
+}
+
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+}
+
+func (f *biquad) filter(x []float64) {
+	var x1, x2, y1, y2 float64
+	for i, v := range x {
+		y := f.b0*v + f.b1*x1 + f.b2*x2 - f.a1*y1 - f.a2*y2
+		x2, x1 = x1, v
+		y2, y1 = y1, y
+		x[i] = y
+	}
+}
+
+// kWeighting returns the two K-weighting filters of BS.1770 for a sample rate.
+func kWeighting(sampleRate int) (biquad, biquad) {
+	// high shelf, modeling the acoustic effect of the head
+	k := math.Tan(math.Pi * 1681.974450955533 / float64(sampleRate))
+	q := 0.7071752369554196
+	vh := math.Pow(10, 3.999843853973347/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf := biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+	// high pass
+	k = math.Tan(math.Pi * 38.13547087602444 / float64(sampleRate))
+	q = 0.5003270373238773
+	a0 = 1 + k/q + k*k
+	highPass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+	return shelf, highPass
+}
+
+// channelWeight returns the BS.1770 weight of a channel, assuming the libav channel order:
+// FL FR FC LFE BL BR for 5.1, and FL FR FC LFE BL BR SL SR for 7.1.
+func channelWeight(channel int, channels int) float64 {
+	switch {
+	case (channels == 6 || channels == 8) && channel == 3:
+		// LFE
+		return 0
+	case channels == 8 && channel >= 4:
+		// back and side channels
+		return 1.41
+	case channels >= 5 && channel >= channels-2:
+		// surround channels
+		return 1.41
+	default:
+		return 1
+	}
+}
+
+func loudnessOf(power float64) float64 {
+	return -0.691 + 10*math.Log10(power)
+}
+
+// gatedLoudness returns the loudness of the blocks passing the absolute gate and
+// the relative gate relative to their mean.
+func gatedLoudness(blocks []float64, gate float64) float64 {
+	mean := func(threshold float64) float64 {
+		var sum float64
+		var n int
+		for _, power := range blocks {
+			if loudnessOf(power) > threshold {
+				sum += power
+				n++
+			}
+		}
+		if n == 0 {
+			return 0
+		}
+		return sum / float64(n)
+	}
+	return loudnessOf(mean(math.Max(absoluteGate, loudnessOf(mean(absoluteGate))+gate)))
+}
+
+/*
+MeasureLoudness measures the loudness of a Song per EBU R128 / ITU-R BS.1770.
+
+Channels are weighted assuming the libav channel order (the LFE channel of 5.1 and 7.1
+songs is ignored).
+
+If the Song is shorter than a 400ms gating block, MeasureLoudness returns an error.
+If the Song has no samples, MeasureLoudness returns ErrNoSamples.
+*/
+func MeasureLoudness(song *Song) (*Loudness, error) {
+	channels, err := song.channelSamples()
+	if err != nil {
+		return nil, err
+	}
+
+	var samplePeak, truePeak float64
+	subBlock := song.SampleRate / 10
+	if subBlock == 0 {
+		subBlock = 1
+	}
+	// weighted mean square power of each 100ms sub-block
+	subBlocks := make([]float64, len(channels[0])/subBlock)
+	for c, channel := range channels {
+		for _, v := range channel {
+			samplePeak = math.Max(samplePeak, math.Abs(v))
+		}
+		truePeak = math.Max(truePeak, interpolatedPeak(channel, song.SampleRate))
+
+		weight := channelWeight(c, len(channels))
+		if weight == 0 {
+			continue
+		}
+		weighted := make([]float64, len(channel))
+		copy(weighted, channel)
+		shelf, highPass := kWeighting(song.SampleRate)
+		shelf.filter(weighted)
+		highPass.filter(weighted)
+		for i := range subBlocks {
+			var sum float64
+			for _, v := range weighted[i*subBlock : (i+1)*subBlock] {
+				sum += v * v
+			}
+			subBlocks[i] += weight * sum / float64(subBlock)
+		}
+	}
+
+	blocks := slidingMeans(subBlocks, 4)
+	if len(blocks) == 0 {
+		return nil, errors.New("bliss: song is too short to measure its loudness")
+	}
+	loudness := &Loudness{
+		blocks:     blocks,
+		SamplePeak: 20 * math.Log10(samplePeak),
+		TruePeak:   20 * math.Log10(math.Max(samplePeak, truePeak)),
+	}
+	loudness.Integrated = gatedLoudness(loudness.blocks, relativeGate)
+	loudness.TrackGain = replayGainReference - loudness.Integrated
+	loudness.Range = loudnessRange(slidingMeans(subBlocks, 30))
+	return loudness, nil
+}
+
+// slidingMeans returns the means of every n consecutive values of x.
+func slidingMeans(x []float64, n int) []float64 {
+	if len(x) < n {
+		return nil
+	}
+	means := make([]float64, len(x)-n+1)
+	var sum float64
+	for i, v := range x {
+		sum += v
+		if i >= n {
+			sum -= x[i-n]
+		}
+		if i >= n-1 {
+			means[i-n+1] = sum / float64(n)
+		}
+	}
+	return means
+}
+
+// loudnessRange returns the loudness range of short-term (3s) blocks, per EBU Tech 3342.
+func loudnessRange(blocks []float64) float64 {
+	var sum float64
+	var n int
+	for _, power := range blocks {
+		if loudnessOf(power) > absoluteGate {
+			sum += power
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	gate := loudnessOf(sum/float64(n)) + rangeGate
+	var loudnesses []float64
+	for _, power := range blocks {
+		if l := loudnessOf(power); l > absoluteGate && l > gate {
+			loudnesses = append(loudnesses, l)
+		}
+	}
+	if len(loudnesses) == 0 {
+		return 0
+	}
+	sort.Float64s(loudnesses)
+	percentile := func(p float64) float64 {
+		return loudnesses[int(math.Round(p*float64(len(loudnesses)-1)))]
+	}
+	return percentile(0.95) - percentile(0.10)
+}
+
+// interpolatedPeak returns the highest absolute value of x oversampled 4 times
+// (2 times from 96 kHz), using a windowed sinc interpolation filter.
+func interpolatedPeak(x []float64, sampleRate int) float64 {
+	factor := 4
+	if sampleRate >= 96000 {
+		factor = 2
+	}
+	if sampleRate >= 192000 {
+		return 0
+	}
+	// filter[phase][tap] interpolates at position tap-truePeakTaps/2+phase/factor
+	filter := make([][]float64, factor)
+	for phase := range filter {
+		filter[phase] = make([]float64, truePeakTaps)
+		for tap := range filter[phase] {
+			t := float64(truePeakTaps/2-1-tap) + float64(phase)/float64(factor)
+			window := 0.5 + 0.5*math.Cos(math.Pi*t/float64(truePeakTaps/2))
+			sinc := 1.0
+			if t != 0 {
+				sinc = math.Sin(math.Pi*t) / (math.Pi * t)
+			}
+			filter[phase][tap] = sinc * window
+		}
+	}
+	var peak float64
+	for i := truePeakTaps; i <= len(x); i++ {
+		history := x[i-truePeakTaps : i]
+		for _, coefficients := range filter[1:] {
+			var v float64
+			for tap, c := range coefficients {
+				v += c * history[tap]
+			}
+			peak = math.Max(peak, math.Abs(v))
+		}
+	}
+	return peak
+}
+
+/*
+AlbumGain returns the ReplayGain 2.0 album gain of songs whose loudness was measured
+with MeasureLoudness, in dB, and the album peak, in dBTP.
+
+The album gain is computed from the gated loudness of all the songs, as if they were
+a single song. If all the songs are silent, or if there are no songs, the gain is +Inf
+and the peak is -Inf.
+*/
+func AlbumGain(songs ...*Loudness) (gain float64, peak float64) {
+	var blocks []float64
+	peak = math.Inf(-1)
+	for _, song := range songs {
+		blocks = append(blocks, song.blocks...)
+		peak = math.Max(peak, song.TruePeak)
+	}
+	return replayGainReference - gatedLoudness(blocks, relativeGate), peak
+}
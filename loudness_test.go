@@ -0,0 +1,66 @@
+package bliss
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMeasureLoudness(t *testing.T) {
+	const rate = 48000
+	// a 1 kHz sine at -20 dBFS in a single channel measures -23.01 LUFS
+	song := newTestSong(rate, 1, sine(rate, 10*rate, 997, 0.1))
+	loudness, err := MeasureLoudness(song)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNear(t, -23.01, loudness.Integrated, 0.1, "integrated loudness")
+	assertNear(t, 0, loudness.Range, 0.1, "loudness range")
+	assertNear(t, -20, loudness.SamplePeak, 0.1, "sample peak")
+	assertNear(t, -20, loudness.TruePeak, 0.1, "true peak")
+	assertNear(t, 5.01, loudness.TrackGain, 0.1, "track gain")
+
+	quiet := newTestSong(rate, 1, sine(rate, 10*rate, 997, 0.05))
+	quietLoudness, err := MeasureLoudness(quiet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gain, peak := AlbumGain(loudness, quietLoudness)
+	// both songs pass the relative gate: the album loudness is their mean power
+	assertNear(t, -18-(-23.01+10*math.Log10(1.25/2)), gain, 0.1, "album gain")
+	assertNear(t, -20, peak, 0.1, "album peak")
+}
+
+func TestMeasureLoudnessSilence(t *testing.T) {
+	const rate = 48000
+	loudness, err := MeasureLoudness(newTestSong(rate, 1, make([]float64, 10*rate)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(loudness.Integrated, -1) || !math.IsInf(loudness.TrackGain, 1) ||
+		!math.IsInf(loudness.SamplePeak, -1) || !math.IsInf(loudness.TruePeak, -1) {
+		t.Errorf("expected infinite measurements for a silent song, got: %+v", loudness)
+	}
+	gain, peak := AlbumGain()
+	if !math.IsInf(gain, 1) || !math.IsInf(peak, -1) {
+		t.Errorf("expected an infinite album gain without songs, got: %f, %f", gain, peak)
+	}
+}
+
+func TestMeasureLoudnessShort(t *testing.T) {
+	const rate = 48000
+	if _, err := MeasureLoudness(newTestSong(rate, 1, sine(rate, rate/4, 997, 0.1))); err == nil {
+		t.Error("expected an error measuring a song shorter than a gating block")
+	}
+}
+
+func TestChannelWeight(t *testing.T) {
+	for channels, weights := range map[int][]float64{
+		2: {1, 1},
+		6: {1, 1, 1, 0, 1.41, 1.41},
+		8: {1, 1, 1, 0, 1.41, 1.41, 1.41, 1.41},
+	} {
+		for channel, weight := range weights {
+			assertNear(t, weight, channelWeight(channel, channels), 0, "channel weight")
+		}
+	}
+}
@@ -0,0 +1,445 @@
+package bliss
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// lanczosSteps is the maximum size of the basis of the Lanczos method.
+	lanczosSteps = 100
+	// jacobiSweeps is the maximum number of sweeps of the Jacobi eigenvalue method.
+	jacobiSweeps = 50
+	// defaultScatterWidth and defaultScatterHeight are the default size of scatter plots.
+	defaultScatterWidth  = 800
+	defaultScatterHeight = 600
+)
+
+// scatterColors is the palette of the labels of scatter plots.
+var scatterColors = [...]string{
+	"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+	"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf",
+}
+
+/*
+ProjectionOptions stores the options of Project.
+*/
+type ProjectionOptions struct {
+	/*
+		MDS projects the vectors with classical multidimensional scaling of their distance
+		matrix, rather than with PCA. Its time and memory are quadratic in the number of vectors:
+		a few seconds and 200 MB for 5000 vectors, so larger libraries are better projected
+		with PCA, or sampled.
+	*/
+	MDS bool
+	/*
+		Distance is the distance used by MDS. Defaults to Distance.
+	*/
+	Distance func(a ForceVector, b ForceVector) float32
+}
+
+/*
+Projection is a projection of force vectors to 2-D, e.g. to draw a mood map of a library.
+*/
+type Projection struct {
+	/*
+		Points stores the coordinates of each vector.
+	*/
+	Points [][2]float64
+	/*
+		ExplainedVariance stores the share of the variance of the vectors (or of the
+		scalar products derived from their distances, for MDS) explained by each axis.
+	*/
+	ExplainedVariance [2]float64
+	/*
+		Loadings stores the weight of each dimension in each axis: the principal
+		components for PCA, or the correlations between the dimensions and the axes for MDS.
+	*/
+	Loadings [2]ForceVector
+}
+
+func forceValues(forceVector ForceVector) [len(forceDimensions)]float64 {
+	var values [len(forceDimensions)]float64
+	for i := range forceDimensions {
+		values[i] = float64(forceDimensions[i].value(forceVector))
+	}
+	return values
+}
+
+func newForceVectorOf(values []float64) ForceVector {
+	return ForceVector{
+		Tempo:     float32(values[0]),
+		Attack:    float32(values[1]),
+		Amplitude: float32(values[2]),
+		Frequency: float32(values[3]),
+	}
+}
+
+// jacobiEigen returns the eigenvalues of the symmetric matrix a, in decreasing order, and
+// their eigenvectors, vectors[k] being the eigenvector of values[k]. a is modified.
+func jacobiEigen(a [][]float64) ([]float64, [][]float64) {
+	n := len(a)
+	v := make([][]float64, n)
+	for i := range v {
+		v[i] = make([]float64, n)
+		v[i][i] = 1
+	}
+	for sweep := 0; sweep < jacobiSweeps; sweep++ {
+		var off float64
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				off += a[p][q] * a[p][q]
+			}
+		}
+		if off < 1e-22 {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := math.Copysign(1, theta) / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p], a[k][q] = c*akp-s*akq, s*akp+c*akq
+				}
+				for k := 0; k < n; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k], a[q][k] = c*apk-s*aqk, s*apk+c*aqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p], v[k][q] = c*vkp-s*vkq, s*vkp+c*vkq
+				}
+			}
+		}
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return a[order[i]][order[i]] > a[order[j]][order[j]]
+	})
+	values := make([]float64, n)
+	vectors := make([][]float64, n)
+	for k, i := range order {
+		values[k] = a[i][i]
+		vectors[k] = make([]float64, n)
+		for j := range vectors[k] {
+			vectors[k][j] = v[j][i]
+		}
+	}
+	return values, vectors
+}
+
+// orthogonalize removes the components of v along the orthonormal vectors, twice for
+// numerical stability.
+func orthogonalize(v []float64, vectors [][]float64) {
+	for pass := 0; pass < 2; pass++ {
+		for _, u := range vectors {
+			var dot float64
+			for i := range v {
+				dot += u[i] * v[i]
+			}
+			for i := range v {
+				v[i] -= dot * u[i]
+			}
+		}
+	}
+}
+
+// lanczosEigen returns the count largest eigenvalues of the symmetric matrix a, and their
+// eigenvectors, with the Lanczos method with full reorthogonalization. Eigenvalues missing
+// because a has a lower rank are zero, with zero eigenvectors.
+func lanczosEigen(a [][]float64, count int) ([]float64, [][]float64) {
+	n := len(a)
+	v := make([]float64, n)
+	for i := range v {
+		// a deterministic start, unlikely to be orthogonal to the eigenvectors
+		v[i] = 1 + float64(i%7)/7
+	}
+	normalize(v)
+
+	var basis [][]float64
+	var alphas, betas []float64
+	var ritzValues []float64
+	var ritzVectors [][]float64
+	for len(basis) < n && len(basis) < lanczosSteps {
+		basis = append(basis, v)
+		w := make([]float64, n)
+		var alpha float64
+		for i, row := range a {
+			for j, x := range row {
+				w[i] += x * v[j]
+			}
+			alpha += w[i] * v[i]
+		}
+		alphas = append(alphas, alpha)
+		orthogonalize(w, basis)
+		var beta float64
+		for _, x := range w {
+			beta += x * x
+		}
+		beta = math.Sqrt(beta)
+
+		// the eigenpairs of the tridiagonal matrix of the basis
+		m := len(basis)
+		t := make([][]float64, m)
+		for i := range t {
+			t[i] = make([]float64, m)
+			t[i][i] = alphas[i]
+			if i > 0 {
+				t[i][i-1], t[i-1][i] = betas[i-1], betas[i-1]
+			}
+		}
+		ritzValues, ritzVectors = jacobiEigen(t)
+		scale := math.Max(math.Abs(ritzValues[0]), math.Abs(ritzValues[m-1]))
+		if beta <= 1e-12*scale || beta == 0 {
+			// the basis spans an invariant subspace
+			break
+		}
+		converged := m >= count
+		for k := 0; k < count && k < m; k++ {
+			// the residual of the Ritz pair
+			if beta*math.Abs(ritzVectors[k][m-1]) > 1e-10*scale {
+				converged = false
+			}
+		}
+		if converged {
+			break
+		}
+		betas = append(betas, beta)
+		for i := range w {
+			w[i] /= beta
+		}
+		v = w
+	}
+
+	values := make([]float64, count)
+	vectors := make([][]float64, count)
+	for k := range vectors {
+		vectors[k] = make([]float64, n)
+		if k >= len(ritzValues) {
+			continue
+		}
+		values[k] = ritzValues[k]
+		for j, u := range basis {
+			for i := range u {
+				vectors[k][i] += ritzVectors[k][j] * u[i]
+			}
+		}
+	}
+	return values, vectors
+}
+
+/*
+Project projects force vectors to 2-D, with PCA (principal component analysis) or classical
+MDS (multidimensional scaling), e.g. to draw a mood map of a library.
+
+Project panics if there are no vectors.
+*/
+func Project(forceVectors []ForceVector, options ProjectionOptions) *Projection {
+	if len(forceVectors) == 0 {
+		panic("bliss: no force vectors to project")
+	}
+	n := len(forceVectors)
+	dimensions := len(forceDimensions)
+	values := make([][len(forceDimensions)]float64, n)
+	var mean [len(forceDimensions)]float64
+	for i, forceVector := range forceVectors {
+		values[i] = forceValues(forceVector)
+		for d, x := range values[i] {
+			mean[d] += x / float64(n)
+		}
+	}
+	for i := range values {
+		for d := range values[i] {
+			values[i][d] -= mean[d]
+		}
+	}
+
+	projection := &Projection{
+		Points: make([][2]float64, n),
+	}
+	if !options.MDS {
+		covariance := make([][]float64, dimensions)
+		for d := range covariance {
+			covariance[d] = make([]float64, dimensions)
+			for e := range covariance[d] {
+				for i := range values {
+					covariance[d][e] += values[i][d] * values[i][e] / float64(n)
+				}
+			}
+		}
+		eigenvalues, eigenvectors := jacobiEigen(covariance)
+		var total float64
+		for _, value := range eigenvalues {
+			total += value
+		}
+		for k := 0; k < 2; k++ {
+			if total > 0 {
+				projection.ExplainedVariance[k] = eigenvalues[k] / total
+			}
+			projection.Loadings[k] = newForceVectorOf(eigenvectors[k])
+			for i := range values {
+				for d, x := range values[i] {
+					projection.Points[i][k] += x * eigenvectors[k][d]
+				}
+			}
+		}
+		return projection
+	}
+
+	distance := options.Distance
+	if distance == nil {
+		distance = func(a ForceVector, b ForceVector) float32 {
+			return float32(forceDistance(a, b))
+		}
+	}
+	// double centering of the squared distances
+	b := make([][]float64, n)
+	for i := range b {
+		b[i] = make([]float64, n)
+	}
+	for i := range b {
+		for j := i + 1; j < n; j++ {
+			d := float64(distance(forceVectors[i], forceVectors[j]))
+			b[i][j] = -d * d / 2
+			b[j][i] = b[i][j]
+		}
+	}
+	rows := make([]float64, n)
+	var all float64
+	for i := range b {
+		for _, x := range b[i] {
+			rows[i] += x / float64(n)
+		}
+		all += rows[i] / float64(n)
+	}
+	var trace float64
+	for i := range b {
+		for j := range b[i] {
+			b[i][j] += all - rows[i] - rows[j]
+		}
+		trace += b[i][i]
+	}
+	eigenvalues, eigenvectors := lanczosEigen(b, 2)
+	for k := 0; k < 2; k++ {
+		if eigenvalues[k] <= 0 {
+			continue
+		}
+		if trace > 0 {
+			projection.ExplainedVariance[k] = eigenvalues[k] / trace
+		}
+		scale := math.Sqrt(eigenvalues[k])
+		axis := make([]float64, n)
+		for i := range projection.Points {
+			projection.Points[i][k] = eigenvectors[k][i] * scale
+			axis[i] = projection.Points[i][k]
+		}
+		loadings := make([]float64, dimensions)
+		column := make([]float64, n)
+		for d := range loadings {
+			for i := range values {
+				column[i] = values[i][d]
+			}
+			loadings[d] = correlation(column, axis)
+		}
+		projection.Loadings[k] = newForceVectorOf(loadings)
+	}
+	return projection
+}
+
+/*
+ScatterOptions stores the options of Projection.WriteSVG.
+*/
+type ScatterOptions struct {
+	/*
+		Width and Height are the size of the plot, in pixels. They default to 800 and 600.
+	*/
+	Width, Height int
+	/*
+		Labels stores the label of each point, e.g. the Genre of the songs or their cluster.
+		Points are colored by label, and the labels are listed in a legend. Empty labels
+		are drawn in gray, without legend.
+	*/
+	Labels []string
+	/*
+		Titles stores the title of each point, e.g. the Title of the songs, shown as a tooltip.
+	*/
+	Titles []string
+}
+
+func escapeXML(s string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(s))
+	return escaped.String()
+}
+
+/*
+WriteSVG renders the projection as an SVG scatter plot to w.
+*/
+func (projection *Projection) WriteSVG(w io.Writer, options ScatterOptions) error {
+	width, height := options.Width, options.Height
+	if width == 0 {
+		width = defaultScatterWidth
+	}
+	if height == 0 {
+		height = defaultScatterHeight
+	}
+	const margin = 20
+	minX, maxX, minY, maxY := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	for _, point := range projection.Points {
+		minX, maxX = math.Min(minX, point[0]), math.Max(maxX, point[0])
+		minY, maxY = math.Min(minY, point[1]), math.Max(maxY, point[1])
+	}
+	scale := func(v float64, min float64, max float64, size int) float64 {
+		if max <= min {
+			return float64(size) / 2
+		}
+		return margin + (v-min)/(max-min)*float64(size-2*margin)
+	}
+
+	colors := make(map[string]string)
+	var legend []string
+	for _, label := range options.Labels {
+		if _, ok := colors[label]; !ok && label != "" {
+			colors[label] = scatterColors[len(legend)%len(scatterColors)]
+			legend = append(legend, label)
+		}
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height)
+	fmt.Fprintf(out, `<rect width="%d" height="%d" fill="white"/>`+"\n", width, height)
+	for i, point := range projection.Points {
+		color := "#999999"
+		if i < len(options.Labels) && options.Labels[i] != "" {
+			color = colors[options.Labels[i]]
+		}
+		// the y axis goes up
+		x, y := scale(point[0], minX, maxX, width), float64(height)-scale(point[1], minY, maxY, height)
+		fmt.Fprintf(out, `<circle cx="%.1f" cy="%.1f" r="4" fill="%s" fill-opacity="0.8">`, x, y, color)
+		if i < len(options.Titles) && options.Titles[i] != "" {
+			fmt.Fprintf(out, `<title>%s</title>`, escapeXML(options.Titles[i]))
+		}
+		fmt.Fprint(out, "</circle>\n")
+	}
+	for i, label := range legend {
+		y := margin + 16*i
+		fmt.Fprintf(out, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`, margin, y, colors[label])
+		fmt.Fprintf(out, `<text x="%d" y="%d" font-family="sans-serif" font-size="12">%s</text>`+"\n", margin+14, y+10, escapeXML(label))
+	}
+	fmt.Fprint(out, "</svg>\n")
+	return out.Flush()
+}
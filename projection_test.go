@@ -0,0 +1,101 @@
+package bliss
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestJacobiEigen(t *testing.T) {
+	values, vectors := jacobiEigen([][]float64{
+		{2, 1, 0},
+		{1, 2, 0},
+		{0, 0, 5},
+	})
+	assertNear(t, 5, values[0], 1e-9, "first eigenvalue")
+	assertNear(t, 3, values[1], 1e-9, "second eigenvalue")
+	assertNear(t, 1, values[2], 1e-9, "third eigenvalue")
+	assertNear(t, 1, math.Abs(vectors[0][2]), 1e-9, "first eigenvector")
+	assertNear(t, math.Sqrt(0.5), math.Abs(vectors[1][0]), 1e-9, "second eigenvector")
+}
+
+func TestLanczosEigen(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	const n = 50
+	a, b := make([][]float64, n), make([][]float64, n)
+	for i := range a {
+		a[i], b[i] = make([]float64, n), make([]float64, n)
+	}
+	for i := range a {
+		for j := 0; j <= i; j++ {
+			x := random.NormFloat64()
+			a[i][j], a[j][i] = x, x
+			b[i][j], b[j][i] = x, x
+		}
+	}
+	expected, _ := jacobiEigen(b)
+	values, vectors := lanczosEigen(a, 2)
+	for k := range values {
+		assertNear(t, expected[k], values[k], 1e-9, "eigenvalue")
+		// a v = value v
+		for i, row := range a {
+			var x float64
+			for j, y := range row {
+				x += y * vectors[k][j]
+			}
+			assertNear(t, values[k]*vectors[k][i], x, 1e-6, "eigenvector")
+		}
+	}
+}
+
+func TestProject(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	var forceVectors []ForceVector
+	for i := 0; i < 100; i++ {
+		// most variance along tempo and attack together, then along frequency
+		x, y := 10*random.NormFloat64(), 3*random.NormFloat64()
+		forceVectors = append(forceVectors, ForceVector{
+			Tempo:     float32(x),
+			Attack:    float32(x),
+			Amplitude: float32(0.1 * random.NormFloat64()),
+			Frequency: float32(y),
+		})
+	}
+	pca := Project(forceVectors, ProjectionOptions{})
+	assertInt(t, 100, len(pca.Points), "points count")
+	if pca.ExplainedVariance[0] < 0.9 || pca.ExplainedVariance[0]+pca.ExplainedVariance[1] < 0.999 {
+		t.Errorf("unexpected explained variance: %v", pca.ExplainedVariance)
+	}
+	assertNear(t, math.Sqrt(0.5), math.Abs(float64(pca.Loadings[0].Tempo)), 0.01, "first axis tempo loading")
+	assertNear(t, 1, math.Abs(float64(pca.Loadings[1].Frequency)), 0.01, "second axis frequency loading")
+
+	mds := Project(forceVectors, ProjectionOptions{MDS: true})
+	// with euclidean distances, classical MDS is equivalent to PCA, up to the sign of the axes
+	for k := 0; k < 2; k++ {
+		assertNear(t, pca.ExplainedVariance[k], mds.ExplainedVariance[k], 1e-3, "MDS explained variance")
+		sign := math.Copysign(1, pca.Points[0][k]*mds.Points[0][k])
+		for i := range pca.Points {
+			assertNear(t, pca.Points[i][k], sign*mds.Points[i][k], 1e-2, "MDS point")
+		}
+	}
+	assertNear(t, 1, math.Abs(float64(mds.Loadings[0].Tempo)), 0.01, "MDS first axis tempo loading")
+
+	var buffer bytes.Buffer
+	labels := make([]string, len(forceVectors))
+	titles := make([]string, len(forceVectors))
+	for i := range labels {
+		labels[i] = []string{"Rock", "Jazz & Blues", ""}[i%3]
+		titles[i] = "<song>"
+	}
+	if err := pca.WriteSVG(&buffer, ScatterOptions{Labels: labels, Titles: titles}); err != nil {
+		t.Fatal(err)
+	}
+	svg := buffer.String()
+	assertInt(t, 100, strings.Count(svg, "<circle"), "circles count")
+	assertInt(t, 2, strings.Count(svg, "<text"), "legend entries count")
+	if !strings.Contains(svg, "Jazz &amp; Blues") || !strings.Contains(svg, "&lt;song&gt;") {
+		t.Error("expected labels and titles to be escaped")
+	}
+}
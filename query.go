@@ -0,0 +1,338 @@
+package bliss
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultQueryResults is the default number of songs returned by queries.
+	defaultQueryResults = 10
+	// reliableDuration is the duration from which the tempo and attack ratings of a snippet
+	// are fully reliable.
+	reliableDuration = 30 * time.Second
+)
+
+/*
+Index is an in-memory index of analyzed songs, to find the songs closest to a ForceVector
+or to an audio snippet.
+
+Index is safe for concurrent use.
+*/
+type Index struct {
+	mutex sync.RWMutex
+	songs []*Song
+}
+
+/*
+NewIndex returns an Index of the given analyzed songs.
+
+Indexed songs only keep their analysis results and metadata: their Samples are nil,
+and they do not own any native memory, so the songs can be closed afterwards.
+*/
+func NewIndex(songs ...*Song) *Index {
+	index := &Index{}
+	index.Add(songs...)
+	return index
+}
+
+/*
+Add adds analyzed songs to the index, as in NewIndex.
+*/
+func (index *Index) Add(songs ...*Song) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	for _, song := range songs {
+		index.songs = append(index.songs, detachSong(song))
+	}
+}
+
+/*
+Len returns the number of songs in the index.
+*/
+func (index *Index) Len() int {
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+	return len(index.songs)
+}
+
+/*
+Match is a song returned by a query, with its distance to the query.
+*/
+type Match struct {
+	/*
+		Song is the indexed song.
+	*/
+	Song *Song
+	/*
+		Distance is the distance between the song and the query, computed as by Distance
+		on the vectors scaled by the query weights.
+	*/
+	Distance float32
+	/*
+		Differences stores the difference between each rating of the song and of the
+		query (song minus query), without weights.
+	*/
+	Differences ForceVector
+}
+
+/*
+Nearest returns the k indexed songs closest to forceVector, closest first. If k is
+negative, Nearest returns no songs.
+
+Each rating of the vectors is scaled by the corresponding rating of weights before
+computing their Distance, e.g. to ignore a rating by setting its weight to 0.
+*/
+func (index *Index) Nearest(forceVector ForceVector, weights ForceVector, k int) []Match {
+	scale := func(v ForceVector) ForceVector {
+		return ForceVector{
+			Tempo:     v.Tempo * weights.Tempo,
+			Attack:    v.Attack * weights.Attack,
+			Amplitude: v.Amplitude * weights.Amplitude,
+			Frequency: v.Frequency * weights.Frequency,
+		}
+	}
+	query := scale(forceVector)
+
+	index.mutex.RLock()
+	matches := make([]Match, len(index.songs))
+	for i, song := range index.songs {
+		v := song.ForceVector
+		matches[i] = Match{
+			Song:     song,
+			Distance: float32(forceDistance(query, scale(v))),
+			Differences: ForceVector{
+				Tempo:     v.Tempo - forceVector.Tempo,
+				Attack:    v.Attack - forceVector.Attack,
+				Amplitude: v.Amplitude - forceVector.Amplitude,
+				Frequency: v.Frequency - forceVector.Frequency,
+			},
+		}
+	}
+	index.mutex.RUnlock()
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Distance < matches[j].Distance
+	})
+	if k < 0 {
+		k = 0
+	}
+	if k < len(matches) {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+/*
+FeatureIndex is an in-memory index of songs by FeatureVector, e.g. the features returned
+by ExtractFeatures, to find the songs closest to a FeatureVector. It is the counterpart of
+Index for feature vectors.
+
+FeatureIndex is safe for concurrent use.
+*/
+type FeatureIndex struct {
+	mutex          sync.RWMutex
+	normalizer     *Normalizer
+	songs          []*Song
+	featureVectors []FeatureVector
+}
+
+/*
+NewFeatureIndex returns an empty FeatureIndex. If normalizer is not nil, the indexed
+and queried vectors are standardized with it before computing their distance.
+*/
+func NewFeatureIndex(normalizer *Normalizer) *FeatureIndex {
+	return &FeatureIndex{
+		normalizer: normalizer,
+	}
+}
+
+/*
+Add adds a song to the index, with its feature vector. As in NewIndex, the indexed song
+only keeps its analysis results and metadata.
+
+Add panics if the vector is not comparable with the vectors already indexed, or with
+the Normalizer of the index.
+*/
+func (index *FeatureIndex) Add(song *Song, featureVector FeatureVector) {
+	if index.normalizer != nil {
+		featureVector = index.normalizer.Normalize(featureVector)
+	}
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	if len(index.featureVectors) > 0 {
+		checkComparable(index.featureVectors[0], featureVector)
+	}
+	index.songs = append(index.songs, detachSong(song))
+	index.featureVectors = append(index.featureVectors, featureVector)
+}
+
+/*
+Len returns the number of songs in the index.
+*/
+func (index *FeatureIndex) Len() int {
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+	return len(index.songs)
+}
+
+/*
+FeatureMatch is a song returned by FeatureIndex.Nearest, with its distance to the query.
+*/
+type FeatureMatch struct {
+	/*
+		Song is the indexed song.
+	*/
+	Song *Song
+	/*
+		Distance is the distance between the song and the query, computed with
+		FeatureDistance on the (normalized) vectors.
+	*/
+	Distance float64
+}
+
+/*
+Nearest returns the k indexed songs closest to featureVector, closest first. If k is
+negative, Nearest returns no songs.
+
+Nearest panics if the vector is not comparable with the indexed vectors.
+*/
+func (index *FeatureIndex) Nearest(featureVector FeatureVector, k int) []FeatureMatch {
+	if index.normalizer != nil {
+		featureVector = index.normalizer.Normalize(featureVector)
+	}
+	index.mutex.RLock()
+	matches := make([]FeatureMatch, len(index.songs))
+	for i, song := range index.songs {
+		matches[i] = FeatureMatch{
+			Song:     song,
+			Distance: FeatureDistance(featureVector, index.featureVectors[i]),
+		}
+	}
+	index.mutex.RUnlock()
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Distance < matches[j].Distance
+	})
+	if k < 0 {
+		k = 0
+	}
+	if k < len(matches) {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+/*
+QueryOptions stores the options of Index.QueryFile and Index.QueryReader.
+*/
+type QueryOptions struct {
+	/*
+		K is the maximum number of songs returned. Defaults to 10.
+	*/
+	K int
+	/*
+		Analyzer is the Analyzer used to analyze the snippet. Defaults to DefaultAnalyzer.
+	*/
+	Analyzer Analyzer
+}
+
+/*
+QueryResult is the result of a query by example.
+*/
+type QueryResult struct {
+	/*
+		Song is the analyzed snippet. Its Samples are nil, and it does not own any native memory.
+	*/
+	Song *Song
+	/*
+		Weights stores the weights applied to the ratings, as in Index.Nearest.
+	*/
+	Weights ForceVector
+	/*
+		Matches stores the indexed songs closest to the snippet, closest first.
+	*/
+	Matches []Match
+}
+
+// snippetWeights returns the weights of the ratings of a snippet: the tempo and attack
+// ratings are computed over the whole song, so they are less reliable for short snippets.
+func snippetWeights(duration time.Duration) ForceVector {
+	reliability := float32(math.Min(1, duration.Seconds()/reliableDuration.Seconds()))
+	return ForceVector{
+		Tempo:     reliability,
+		Attack:    reliability,
+		Amplitude: 1,
+		Frequency: 1,
+	}
+}
+
+/*
+QueryFile analyzes the audio snippet stored in filename, and returns the indexed songs
+that sound the closest to it.
+
+Snippets shorter than 30 seconds have their tempo and attack ratings down-weighted
+proportionally to their duration, as these ratings are less reliable for short snippets.
+
+If there is an error analyzing the snippet, QueryFile returns it.
+*/
+func (index *Index) QueryFile(ctx context.Context, filename string, options QueryOptions) (*QueryResult, error) {
+	analyzer := options.Analyzer
+	if analyzer == nil {
+		analyzer = DefaultAnalyzer
+	}
+	k := options.K
+	if k == 0 {
+		k = defaultQueryResults
+	}
+	if k < 0 {
+		return nil, errors.New("bliss: invalid number of results")
+	}
+	song, err := analyzer.Analyze(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	duration := song.length()
+	if duration == 0 {
+		// the samples are not available, e.g. if the snippet was analyzed by a WorkerPool
+		duration = time.Duration(song.Duration) * time.Second
+	}
+	snippet := detachSong(song)
+	// Close clears the finalizer, so the song is not freed again by the GC
+	song.Close()
+
+	weights := snippetWeights(duration)
+	return &QueryResult{
+		Song:    snippet,
+		Weights: weights,
+		Matches: index.Nearest(snippet.ForceVector, weights, k),
+	}, nil
+}
+
+/*
+QueryReader is like QueryFile, reading the audio snippet from r, e.g. an uploaded clip.
+
+The snippet is copied to a temporary file, which is removed before QueryReader returns.
+*/
+func (index *Index) QueryReader(ctx context.Context, r io.Reader, options QueryOptions) (*QueryResult, error) {
+	f, err := ioutil.TempFile("", "bliss-query-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return index.QueryFile(ctx, f.Name(), options)
+}
@@ -0,0 +1,97 @@
+package bliss
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIndexNearest(t *testing.T) {
+	index := NewIndex(
+		&Song{Filename: "far.flac", ForceVector: ForceVector{Amplitude: 5}},
+		&Song{Filename: "close.flac", ForceVector: ForceVector{Amplitude: 1}},
+		&Song{Filename: "closest.flac", ForceVector: ForceVector{Amplitude: 0.5, Tempo: 2}},
+	)
+	assertInt(t, 3, index.Len(), "index length")
+
+	matches := index.Nearest(ForceVector{}, ForceVector{Amplitude: 1}, 2)
+	assertInt(t, 2, len(matches), "matches count")
+	assertString(t, "closest.flac", matches[0].Song.Filename, "closest match")
+	assertString(t, "close.flac", matches[1].Song.Filename, "second match")
+	assertFloat(t, 2, matches[0].Differences.Tempo, "tempo difference")
+
+	// without weights, the tempo of the closest song makes it further
+	matches = index.Nearest(ForceVector{}, ForceVector{Tempo: 1, Amplitude: 1}, 10)
+	assertInt(t, 3, len(matches), "matches count")
+	assertString(t, "close.flac", matches[0].Song.Filename, "closest unweighted match")
+
+	assertInt(t, 0, len(index.Nearest(ForceVector{}, ForceVector{Amplitude: 1}, -1)), "matches count with negative k")
+}
+
+func TestIndexQueryReader(t *testing.T) {
+	const rate = 22050
+	index := NewIndex(
+		&Song{Filename: "slow.flac", ForceVector: ForceVector{Tempo: 1, Amplitude: 1}},
+		&Song{Filename: "fast.flac", ForceVector: ForceVector{Tempo: 4, Amplitude: 1.5}},
+	)
+	analyzer := AnalyzerFunc(func(ctx context.Context, filename string) (*Song, error) {
+		content, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		assertString(t, "snippet", string(content), "snippet content")
+		song := newTestSong(rate, 1, make([]float64, 10*rate))
+		song.ForceVector = ForceVector{Tempo: 4, Amplitude: 1}
+		return song, nil
+	})
+	result, err := index.QueryReader(context.Background(), strings.NewReader("snippet"), QueryOptions{
+		K:        1,
+		Analyzer: analyzer,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFloat(t, float32(10*time.Second)/float32(reliableDuration), result.Weights.Tempo, "tempo weight")
+	assertFloat(t, 1, result.Weights.Amplitude, "amplitude weight")
+	if result.Song.Samples != nil {
+		t.Error("expected the snippet samples to be released")
+	}
+	assertInt(t, 1, len(result.Matches), "matches count")
+	assertString(t, "fast.flac", result.Matches[0].Song.Filename, "closest match")
+}
+
+func TestFeatureIndexNearest(t *testing.T) {
+	names := []string{"centroid", "flatness"}
+	songs := []*Song{{Filename: "centroid.flac"}, {Filename: "flatness.flac"}}
+	vectors := []FeatureVector{
+		{Names: names, Values: []float64{1, 0}},
+		{Names: names, Values: []float64{0, 100}},
+	}
+	query := FeatureVector{Names: names, Values: []float64{0, 0}}
+
+	index := NewFeatureIndex(nil)
+	for i := range songs {
+		index.Add(songs[i], vectors[i])
+	}
+	assertInt(t, 2, index.Len(), "index length")
+	matches := index.Nearest(query, 10)
+	assertInt(t, 2, len(matches), "matches count")
+	assertString(t, "centroid.flac", matches[0].Song.Filename, "closest match")
+	assertNear(t, 1, matches[0].Distance, 1e-9, "closest distance")
+
+	// the flatness varies much more than the centroid over the library
+	normalized := NewFeatureIndex(&Normalizer{
+		Names:      names,
+		Means:      []float64{0, 0},
+		Deviations: []float64{0.1, 100},
+	})
+	for i := range songs {
+		normalized.Add(songs[i], vectors[i])
+	}
+	matches = normalized.Nearest(query, 1)
+	assertInt(t, 1, len(matches), "matches count")
+	assertString(t, "flatness.flac", matches[0].Song.Filename, "closest normalized match")
+	assertInt(t, 0, len(normalized.Nearest(query, -1)), "matches count with negative k")
+}
@@ -0,0 +1,408 @@
+package bliss
+
+import (
+	"encoding/json"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// reportBins is the number of bins of the histograms of reports.
+	reportBins = 20
+	// outlierDeviations is the number of standard deviations from the mean from which
+	// a song is an outlier.
+	outlierDeviations = 3
+)
+
+/*
+Distribution stores the distribution of a rating over a library.
+*/
+type Distribution struct {
+	/*
+		Dimension is the name of the rating: "tempo", "attack", "amplitude", "frequency"
+		(as in ForceVector.FeatureVector), or "force".
+	*/
+	Dimension string
+	/*
+		Min, Max, Mean and Deviation are the minimum, maximum, mean and standard deviation
+		of the rating.
+	*/
+	Min, Max, Mean, Deviation float64
+	/*
+		Quartiles stores the first quartile, the median and the third quartile of the rating.
+	*/
+	Quartiles [3]float64
+	/*
+		Histogram stores the number of songs in each of 20 bins of equal width, from Min to Max.
+	*/
+	Histogram []int
+}
+
+/*
+RatingCounts stores the number of songs of each ForceRating.
+*/
+type RatingCounts struct {
+	Loud, Calm, Unknown int
+}
+
+/*
+GroupMeans stores the mean ratings of a group of songs, e.g. of a genre or an artist.
+*/
+type GroupMeans struct {
+	/*
+		Name is the name of the group, e.g. the genre or the artist. Songs without the tag
+		are grouped with an empty name.
+	*/
+	Name string
+	/*
+		Songs is the number of songs of the group.
+	*/
+	Songs int
+	/*
+		ForceVector stores the mean ratings of the songs of the group.
+	*/
+	ForceVector ForceVector
+	/*
+		Force is the mean force of the songs of the group.
+	*/
+	Force float64
+}
+
+/*
+Outlier is a song with an unusual rating compared to the library.
+*/
+type Outlier struct {
+	/*
+		Filename, Artist and Title identify the song.
+	*/
+	Filename, Artist, Title string
+	/*
+		Dimension is the name of its most unusual rating, as in Distribution.
+	*/
+	Dimension string
+	/*
+		Value is the value of the rating.
+	*/
+	Value float64
+	/*
+		Score is the number of standard deviations between the rating and its mean
+		over the library (its z-score).
+	*/
+	Score float64
+}
+
+/*
+ReportFailure is a file that could not be analyzed.
+*/
+type ReportFailure struct {
+	/*
+		Filename is the path of the file.
+	*/
+	Filename string
+	/*
+		Error is the error returned when analyzing the file.
+	*/
+	Error string
+}
+
+/*
+Report stores statistics about an analyzed library, e.g. to spot analysis regressions
+after ingesting songs.
+
+A Report can be written as JSON with WriteJSON, or as a self-contained HTML page with WriteHTML.
+*/
+type Report struct {
+	/*
+		Songs is the number of analyzed songs.
+	*/
+	Songs int
+	/*
+		Duration is the total duration of the analyzed songs.
+	*/
+	Duration time.Duration
+	/*
+		Distributions stores the distribution of each rating of the ForceVector of the songs,
+		then of their Force.
+	*/
+	Distributions []Distribution
+	/*
+		Ratings stores the number of songs of each ForceRating.
+	*/
+	Ratings RatingCounts
+	/*
+		Genres and Artists store the mean ratings of the songs of each genre and artist,
+		largest groups first.
+	*/
+	Genres, Artists []GroupMeans
+	/*
+		Outliers stores the songs with a rating more than 3 standard deviations away from
+		its mean, most unusual first.
+	*/
+	Outliers []Outlier
+	/*
+		Failures stores the files that could not be analyzed, sorted by filename.
+	*/
+	Failures []ReportFailure
+}
+
+// songRatings returns the ratings of the distributions of reports.
+func songRatings(song *Song) []float64 {
+	values := forceValues(song.ForceVector)
+	return append(values[:], float64(song.Force))
+}
+
+func newDistribution(dimension string, values []float64) Distribution {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	distribution := Distribution{
+		Dimension: dimension,
+		Min:       sorted[0],
+		Max:       sorted[len(sorted)-1],
+		Histogram: make([]int, reportBins),
+	}
+	for _, v := range values {
+		distribution.Mean += v / float64(len(values))
+	}
+	for _, v := range values {
+		distribution.Deviation += (v - distribution.Mean) * (v - distribution.Mean) / float64(len(values))
+	}
+	distribution.Deviation = math.Sqrt(distribution.Deviation)
+	for i := range distribution.Quartiles {
+		distribution.Quartiles[i] = quantile(sorted, float64(i+1)/4)
+	}
+	width := (distribution.Max - distribution.Min) / reportBins
+	for _, v := range values {
+		bin := 0
+		if width > 0 {
+			bin = int((v - distribution.Min) / width)
+		}
+		if bin >= reportBins {
+			bin = reportBins - 1
+		}
+		distribution.Histogram[bin]++
+	}
+	return distribution
+}
+
+// groupMeans returns the mean ratings of the songs of each group, largest groups first.
+func groupMeans(songs []*Song, group func(song *Song) string) []GroupMeans {
+	indices := make(map[string]int)
+	var groups []GroupMeans
+	var sums [][]float64
+	for _, song := range songs {
+		name := group(song)
+		i, ok := indices[name]
+		if !ok {
+			i = len(groups)
+			indices[name] = i
+			groups = append(groups, GroupMeans{Name: name})
+			sums = append(sums, make([]float64, len(forceDimensions)+1))
+		}
+		groups[i].Songs++
+		for d, v := range songRatings(song) {
+			sums[i][d] += v
+		}
+	}
+	for i := range groups {
+		for d := range sums[i] {
+			sums[i][d] /= float64(groups[i].Songs)
+		}
+		groups[i].ForceVector = newForceVectorOf(sums[i])
+		groups[i].Force = sums[i][len(forceDimensions)]
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Songs != groups[j].Songs {
+			return groups[i].Songs > groups[j].Songs
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+/*
+NewReport computes the Report of an analyzed library: songs are the analyzed songs (their
+samples are not used), and failures maps the files that could not be analyzed to their error.
+*/
+func NewReport(songs []*Song, failures map[string]error) *Report {
+	report := &Report{
+		Songs: len(songs),
+	}
+	for filename, err := range failures {
+		report.Failures = append(report.Failures, ReportFailure{
+			Filename: filename,
+			Error:    err.Error(),
+		})
+	}
+	sort.Slice(report.Failures, func(i, j int) bool {
+		return report.Failures[i].Filename < report.Failures[j].Filename
+	})
+	if len(songs) == 0 {
+		return report
+	}
+
+	names := make([]string, 0, len(forceDimensions)+1)
+	for i := range forceDimensions {
+		names = append(names, forceDimensions[i].name)
+	}
+	names = append(names, "force")
+	ratings := make([][]float64, len(names))
+	for _, song := range songs {
+		report.Duration += time.Duration(song.Duration) * time.Second
+		switch song.ForceRating {
+		case Loud:
+			report.Ratings.Loud++
+		case Calm:
+			report.Ratings.Calm++
+		default:
+			report.Ratings.Unknown++
+		}
+		for d, v := range songRatings(song) {
+			ratings[d] = append(ratings[d], v)
+		}
+	}
+	for d, name := range names {
+		report.Distributions = append(report.Distributions, newDistribution(name, ratings[d]))
+	}
+
+	report.Genres = groupMeans(songs, func(song *Song) string { return song.Genre })
+	report.Artists = groupMeans(songs, func(song *Song) string { return song.Artist })
+
+	for i, song := range songs {
+		var outlier *Outlier
+		for d, distribution := range report.Distributions {
+			if distribution.Deviation == 0 {
+				continue
+			}
+			score := (ratings[d][i] - distribution.Mean) / distribution.Deviation
+			if math.Abs(score) > outlierDeviations && (outlier == nil || math.Abs(score) > math.Abs(outlier.Score)) {
+				outlier = &Outlier{
+					Filename:  song.Filename,
+					Artist:    song.Artist,
+					Title:     song.Title,
+					Dimension: distribution.Dimension,
+					Value:     ratings[d][i],
+					Score:     score,
+				}
+			}
+		}
+		if outlier != nil {
+			report.Outliers = append(report.Outliers, *outlier)
+		}
+	}
+	sort.SliceStable(report.Outliers, func(i, j int) bool {
+		return math.Abs(report.Outliers[i].Score) > math.Abs(report.Outliers[j].Score)
+	})
+	return report
+}
+
+/*
+WriteJSON writes the report as JSON to w.
+*/
+func (report *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// histogramBar is a bar of a histogram chart of an HTML report.
+type histogramBar struct {
+	X, Y, Width, Height float64
+	Count               int
+}
+
+const (
+	histogramWidth  = 400
+	histogramHeight = 120
+)
+
+func histogramBars(histogram []int) []histogramBar {
+	max := 1
+	for _, count := range histogram {
+		if count > max {
+			max = count
+		}
+	}
+	width := float64(histogramWidth) / float64(len(histogram))
+	bars := make([]histogramBar, len(histogram))
+	for i, count := range histogram {
+		height := float64(count) / float64(max) * histogramHeight
+		bars[i] = histogramBar{
+			X:      float64(i) * width,
+			Y:      histogramHeight - height,
+			Width:  width - 1,
+			Height: height,
+			Count:  count,
+		}
+	}
+	return bars
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"bars": histogramBars,
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Second).String()
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Library report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.chart { display: inline-block; margin: 0 2em 2em 0; }
+</style>
+</head>
+<body>
+<h1>Library report</h1>
+<p>{{.Songs}} songs analyzed ({{duration .Duration}}), {{len .Failures}} failures.</p>
+<p>Ratings: {{.Ratings.Loud}} loud, {{.Ratings.Calm}} calm, {{.Ratings.Unknown}} unknown.</p>
+
+<h2>Distributions</h2>
+{{range .Distributions}}<div class="chart">
+<h3>{{.Dimension}}</h3>
+<svg xmlns="http://www.w3.org/2000/svg" width="400" height="140" viewBox="0 0 400 140">
+{{range bars .Histogram}}<rect x="{{.X}}" y="{{.Y}}" width="{{.Width}}" height="{{.Height}}" fill="#1f77b4"><title>{{.Count}}</title></rect>
+{{end}}<text x="0" y="136" font-size="12">{{printf "%.2f" .Min}}</text>
+<text x="400" y="136" font-size="12" text-anchor="end">{{printf "%.2f" .Max}}</text>
+</svg>
+<p>mean {{printf "%.2f" .Mean}}, deviation {{printf "%.2f" .Deviation}}, quartiles {{range $i, $q := .Quartiles}}{{if $i}} / {{end}}{{printf "%.2f" $q}}{{end}}</p>
+</div>
+{{end}}
+{{define "groups"}}<table>
+<tr><th>Name</th><th>Songs</th><th>Tempo</th><th>Attack</th><th>Amplitude</th><th>Frequency</th><th>Force</th></tr>
+{{range .}}<tr><td>{{.Name}}</td><td>{{.Songs}}</td><td>{{printf "%.2f" .ForceVector.Tempo}}</td><td>{{printf "%.2f" .ForceVector.Attack}}</td><td>{{printf "%.2f" .ForceVector.Amplitude}}</td><td>{{printf "%.2f" .ForceVector.Frequency}}</td><td>{{printf "%.2f" .Force}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Genres</h2>
+{{template "groups" .Genres}}
+<h2>Artists</h2>
+{{template "groups" .Artists}}
+
+<h2>Outliers</h2>
+<table>
+<tr><th>File</th><th>Artist</th><th>Title</th><th>Rating</th><th>Value</th><th>Score</th></tr>
+{{range .Outliers}}<tr><td>{{.Filename}}</td><td>{{.Artist}}</td><td>{{.Title}}</td><td>{{.Dimension}}</td><td>{{printf "%.2f" .Value}}</td><td>{{printf "%.1f" .Score}}</td></tr>
+{{end}}</table>
+
+<h2>Failures</h2>
+<table>
+<tr><th>File</th><th>Error</th></tr>
+{{range .Failures}}<tr><td>{{.Filename}}</td><td>{{.Error}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+/*
+WriteHTML writes the report as a self-contained HTML page to w, with SVG charts of the
+distributions of the ratings.
+*/
+func (report *Report) WriteHTML(w io.Writer) error {
+	return reportTemplate.Execute(w, report)
+}
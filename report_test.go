@@ -0,0 +1,107 @@
+package bliss
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewReport(t *testing.T) {
+	var songs []*Song
+	for i := 0; i < 40; i++ {
+		v := float32(i%4) - 1.5
+		song := &Song{
+			Filename:    "song.flac",
+			Artist:      []string{"A", "B"}[i%2],
+			Genre:       []string{"Rock", "Rock", "Jazz", ""}[i%4],
+			Duration:    90,
+			ForceVector: ForceVector{Tempo: v, Attack: v, Amplitude: v, Frequency: v},
+			Force:       v,
+			ForceRating: Calm,
+		}
+		if v > 0 {
+			song.ForceRating = Loud
+		}
+		songs = append(songs, song)
+	}
+	songs[0].Filename = "outlier.flac"
+	songs[0].ForceVector.Frequency = 100
+
+	report := NewReport(songs, map[string]error{
+		"b.flac": errors.New("b"),
+		"a.flac": errors.New("a"),
+	})
+	assertInt(t, 40, report.Songs, "songs count")
+	if report.Duration != time.Hour {
+		t.Errorf("unexpected duration: %v", report.Duration)
+	}
+	assertInt(t, 20, report.Ratings.Calm, "calm count")
+	assertInt(t, 20, report.Ratings.Loud, "loud count")
+	assertInt(t, 2, len(report.Failures), "failures count")
+	if report.Failures[0].Filename != "a.flac" || report.Failures[0].Error != "a" {
+		t.Errorf("unexpected failure: %+v", report.Failures[0])
+	}
+
+	assertInt(t, 5, len(report.Distributions), "distributions count")
+	tempo := report.Distributions[0]
+	if tempo.Dimension != "tempo" || report.Distributions[4].Dimension != "force" {
+		t.Errorf("unexpected dimensions: %s, %s", tempo.Dimension, report.Distributions[4].Dimension)
+	}
+	assertNear(t, -1.5, tempo.Min, 1e-9, "tempo min")
+	assertNear(t, 1.5, tempo.Max, 1e-9, "tempo max")
+	assertNear(t, 0, tempo.Mean, 1e-9, "tempo mean")
+	assertNear(t, math.Sqrt(1.25), tempo.Deviation, 1e-9, "tempo deviation")
+	var total int
+	for _, count := range tempo.Histogram {
+		total += count
+	}
+	assertInt(t, 40, total, "histogram total")
+	assertInt(t, 10, tempo.Histogram[0], "first bin")
+	assertInt(t, 10, tempo.Histogram[reportBins-1], "last bin")
+
+	assertInt(t, 3, len(report.Genres), "genres count")
+	if report.Genres[0].Name != "Rock" || report.Genres[0].Songs != 20 {
+		t.Errorf("unexpected first genre: %+v", report.Genres[0])
+	}
+	assertNear(t, -1, float64(report.Genres[0].Force), 1e-6, "rock force")
+	assertInt(t, 2, len(report.Artists), "artists count")
+
+	assertInt(t, 1, len(report.Outliers), "outliers count")
+	if outlier := report.Outliers[0]; outlier.Filename != "outlier.flac" || outlier.Dimension != "frequency" || outlier.Score <= outlierDeviations {
+		t.Errorf("unexpected outlier: %+v", outlier)
+	}
+
+	var buffer bytes.Buffer
+	if err := report.WriteJSON(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buffer.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, 40, decoded.Songs, "decoded songs count")
+
+	buffer.Reset()
+	if err := report.WriteHTML(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	html := buffer.String()
+	for _, s := range []string{"<svg", "outlier.flac", "Jazz", "a.flac"} {
+		if !strings.Contains(html, s) {
+			t.Errorf("HTML report does not contain %q", s)
+		}
+	}
+}
+
+func TestNewReportEmpty(t *testing.T) {
+	report := NewReport(nil, nil)
+	assertInt(t, 0, report.Songs, "songs count")
+	var buffer bytes.Buffer
+	if err := report.WriteHTML(&buffer); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,64 @@
+package bliss
+
+import (
+	"errors"
+)
+
+/*
+ErrNoSamples is returned by functions analyzing the samples of a Song, when the Song
+has no samples, e.g. because it was returned by Cache or WorkerPool.
+*/
+var ErrNoSamples = errors.New("bliss: song has no samples")
+
+// channelSamples returns the samples of each channel of the song, scaled to [-1, 1).
+func (song *Song) channelSamples() ([][]float64, error) {
+	raw := song.sampleBytes()
+	width := song.BytesPerSample
+	if song.Channels <= 0 || song.SampleRate <= 0 || width <= 0 || len(raw) < width*song.Channels {
+		return nil, ErrNoSamples
+	}
+	frames := len(raw) / (width * song.Channels)
+	scale := 1 / float64(int64(1)<<uint(8*width-1))
+	channels := make([][]float64, song.Channels)
+	for c := range channels {
+		channels[c] = make([]float64, frames)
+	}
+	for i := 0; i < frames; i++ {
+		for c := range channels {
+			sample := raw[(i*song.Channels+c)*width:]
+			var v int64
+			if width == 1 {
+				// 8-bit PCM is unsigned
+				v = int64(uint8(sample[0])) - 128
+			} else {
+				v = int64(sample[width-1])
+				for b := width - 2; b >= 0; b-- {
+					v = v<<8 | int64(uint8(sample[b]))
+				}
+			}
+			channels[c][i] = float64(v) * scale
+		}
+	}
+	return channels, nil
+}
+
+// monoSamples returns the samples of the song downmixed to a single channel, scaled to [-1, 1).
+func (song *Song) monoSamples() ([]float64, error) {
+	channels, err := song.channelSamples()
+	if err != nil {
+		return nil, err
+	}
+	if len(channels) == 1 {
+		return channels[0], nil
+	}
+	mono := make([]float64, len(channels[0]))
+	for _, channel := range channels {
+		for i, v := range channel {
+			mono[i] += v
+		}
+	}
+	for i := range mono {
+		mono[i] /= float64(len(channels))
+	}
+	return mono, nil
+}
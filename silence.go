@@ -0,0 +1,163 @@
+package bliss
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// silenceFrame is the duration of the frames whose level is compared to the silence threshold.
+	silenceFrame = 10 * time.Millisecond
+	// defaultSilenceThreshold is the default silence threshold, in dBFS.
+	defaultSilenceThreshold = -60
+	// defaultSilenceMinLength is the default minimum length of interior silences.
+	defaultSilenceMinLength = 2 * time.Second
+)
+
+/*
+Interval is a time range of a Song, from the start of the song.
+*/
+type Interval struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+/*
+SilenceOptions configures DetectSilence.
+*/
+type SilenceOptions struct {
+	/*
+		Threshold is the level under which the song is silent, in dBFS.
+		Zero means -60 dBFS.
+	*/
+	Threshold float64
+	/*
+		MinLength is the minimum length of the silent gaps reported between audible parts.
+		Zero means 2 seconds. Leading and trailing silences are reported regardless of their length.
+	*/
+	MinLength time.Duration
+}
+
+/*
+Silence stores the silent parts of a Song.
+*/
+type Silence struct {
+	/*
+		Leading is the duration of the silence at the start of the song.
+	*/
+	Leading time.Duration
+	/*
+		Trailing is the duration of the silence at the end of the song.
+	*/
+	Trailing time.Duration
+	/*
+		Gaps are the silent parts between audible parts of the song, e.g. before a hidden track.
+	*/
+	Gaps []Interval
+	/*
+		Audible is the part of the song between its leading and trailing silences.
+		It is empty if the song is completely silent.
+	*/
+	Audible Interval
+}
+
+// framePowers returns the mean square of the samples of all channels, per frame of frameLength samples.
+func framePowers(channels [][]float64, frameLength int) []float64 {
+	powers := make([]float64, (len(channels[0])+frameLength-1)/frameLength)
+	for i := range powers {
+		end := (i + 1) * frameLength
+		if end > len(channels[0]) {
+			end = len(channels[0])
+		}
+		var sum float64
+		for _, channel := range channels {
+			for _, v := range channel[i*frameLength : end] {
+				sum += v * v
+			}
+		}
+		powers[i] = sum / float64((end-i*frameLength)*len(channels))
+	}
+	return powers
+}
+
+/*
+DetectSilence finds the silent parts of a Song, whose RMS level over all channels
+stays under options.Threshold.
+
+If the Song has no samples, DetectSilence returns ErrNoSamples.
+*/
+func DetectSilence(song *Song, options SilenceOptions) (*Silence, error) {
+	channels, err := song.channelSamples()
+	if err != nil {
+		return nil, err
+	}
+	threshold := options.Threshold
+	if threshold == 0 {
+		threshold = defaultSilenceThreshold
+	}
+	minLength := options.MinLength
+	if minLength == 0 {
+		minLength = defaultSilenceMinLength
+	}
+	// compare mean squares to avoid computing logarithms
+	powerThreshold := math.Pow(10, threshold/10)
+
+	frameLength := int(silenceFrame.Seconds() * float64(song.SampleRate))
+	if frameLength == 0 {
+		frameLength = 1
+	}
+	powers := framePowers(channels, frameLength)
+	frames := len(powers)
+	silent := make([]bool, frames)
+	for i, power := range powers {
+		silent[i] = power < powerThreshold
+	}
+
+	length := song.length()
+	frameTime := func(frame int) time.Duration {
+		if frame == frames {
+			return length
+		}
+		return time.Duration(float64(frame*frameLength) / float64(song.SampleRate) * float64(time.Second))
+	}
+	first, last := 0, frames
+	for first < frames && silent[first] {
+		first++
+	}
+	for last > first && silent[last-1] {
+		last--
+	}
+	silence := &Silence{
+		Leading:  frameTime(first),
+		Trailing: length - frameTime(last),
+		Audible:  Interval{frameTime(first), frameTime(last)},
+	}
+	for i := first; i < last; {
+		if !silent[i] {
+			i++
+			continue
+		}
+		start := i
+		for i < last && silent[i] {
+			i++
+		}
+		if gap := (Interval{frameTime(start), frameTime(i)}); gap.End-gap.Start >= minLength {
+			silence.Gaps = append(silence.Gaps, gap)
+		}
+	}
+	return silence, nil
+}
+
+/*
+AnalyzeAudible analyzes a Song like AnalyzeRange, but only over the part of the song
+between its leading and trailing silences, as detected by DetectSilence with options.
+
+If the Song has no samples or is completely silent, AnalyzeAudible returns ErrNoSamples.
+*/
+func AnalyzeAudible(song *Song, options SilenceOptions) (*WindowAnalysis, error) {
+	silence, err := DetectSilence(song, options)
+	if err != nil {
+		return nil, err
+	}
+	return AnalyzeRange(song, silence.Audible.Start, silence.Audible.End)
+}
@@ -0,0 +1,44 @@
+package bliss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectSilence(t *testing.T) {
+	const rate = 22050
+	// 1s silence, 2s tone, 3s silence, 1s tone, 2s silence
+	var samples []float64
+	for _, part := range []struct {
+		seconds   int
+		amplitude float64
+	}{{1, 0}, {2, 0.5}, {3, 0}, {1, 0.5}, {2, 0}} {
+		samples = append(samples, sine(rate, part.seconds*rate, 440, part.amplitude)...)
+	}
+	song := newTestSong(rate, 1, samples)
+
+	silence, err := DetectSilence(song, SilenceOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNear(t, 1, silence.Leading.Seconds(), 0.02, "leading silence")
+	assertNear(t, 2, silence.Trailing.Seconds(), 0.02, "trailing silence")
+	assertInt(t, 1, len(silence.Gaps), "gaps count")
+	if len(silence.Gaps) == 1 {
+		assertNear(t, 3, silence.Gaps[0].Start.Seconds(), 0.02, "gap start")
+		assertNear(t, 6, silence.Gaps[0].End.Seconds(), 0.02, "gap end")
+	}
+
+	silence, err = DetectSilence(song, SilenceOptions{MinLength: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, 0, len(silence.Gaps), "long gaps count")
+
+	audible, err := AnalyzeAudible(song, SilenceOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNear(t, 1, audible.Start.Seconds(), 0.02, "audible start")
+	assertNear(t, 7, audible.End.Seconds(), 0.02, "audible end")
+}
@@ -0,0 +1,189 @@
+package bliss
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+const (
+	// defaultSpectrogramSize is the default FFT size of spectrograms.
+	defaultSpectrogramSize = 2048
+	// defaultSpectrogramFloor is the default level, in dBFS, of the lowest color of spectrogram images.
+	defaultSpectrogramFloor = -100
+)
+
+/*
+ColorMap maps a level between 0 (silence) and 1 (full scale) to a color, to render
+spectrograms.
+*/
+type ColorMap func(level float64) color.Color
+
+// gradient returns a ColorMap interpolating linearly between evenly spaced colors.
+func gradient(stops ...color.RGBA) ColorMap {
+	return func(level float64) color.Color {
+		level = math.Max(0, math.Min(1, level))
+		position := level * float64(len(stops)-1)
+		i := int(position)
+		if i >= len(stops)-1 {
+			return stops[len(stops)-1]
+		}
+		t := position - float64(i)
+		a, b := stops[i], stops[i+1]
+		mix := func(x uint8, y uint8) uint8 {
+			return uint8(math.Round(float64(x) + t*(float64(y)-float64(x))))
+		}
+		return color.RGBA{R: mix(a.R, b.R), G: mix(a.G, b.G), B: mix(a.B, b.B), A: 0xFF}
+	}
+}
+
+var (
+	/*
+		GrayColorMap renders levels from black to white.
+	*/
+	GrayColorMap = gradient(color.RGBA{A: 0xFF}, color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF})
+	/*
+		HeatColorMap renders levels from black to white, through red and yellow.
+	*/
+	HeatColorMap = gradient(
+		color.RGBA{A: 0xFF},
+		color.RGBA{R: 0xFF, A: 0xFF},
+		color.RGBA{R: 0xFF, G: 0xFF, A: 0xFF},
+		color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+	)
+	/*
+		ViridisColorMap renders levels from dark purple to yellow, through blue and green.
+	*/
+	ViridisColorMap = gradient(
+		color.RGBA{R: 0x44, G: 0x01, B: 0x54, A: 0xFF},
+		color.RGBA{R: 0x3B, G: 0x52, B: 0x8B, A: 0xFF},
+		color.RGBA{R: 0x21, G: 0x91, B: 0x8C, A: 0xFF},
+		color.RGBA{R: 0x5E, G: 0xC9, B: 0x62, A: 0xFF},
+		color.RGBA{R: 0xFD, G: 0xE7, B: 0x25, A: 0xFF},
+	)
+)
+
+/*
+SpectrogramOptions stores the options of ComputeSpectrogram.
+*/
+type SpectrogramOptions struct {
+	/*
+		FFTSize is the number of samples of each frame, a power of two. Defaults to 2048.
+	*/
+	FFTSize int
+	/*
+		Hop is the number of samples between the start of consecutive frames. Defaults
+		to a quarter of FFTSize, and at least 1.
+	*/
+	Hop int
+	/*
+		ColorMap is the ColorMap used to render the spectrogram. Defaults to HeatColorMap.
+	*/
+	ColorMap ColorMap
+	/*
+		Floor is the level, in dBFS, rendered with the lowest color. Lower levels are
+		clamped to it. Defaults to -100 dBFS.
+	*/
+	Floor float64
+}
+
+/*
+Spectrogram stores the magnitude spectrum of each frame of a Song.
+*/
+type Spectrogram struct {
+	/*
+		Magnitudes stores, for each frame, the magnitude of each frequency bin (FFTSize/2+1
+		bins from 0 Hz to the Nyquist frequency), in dBFS: a full-scale sine is at 0 dBFS.
+	*/
+	Magnitudes [][]float64
+	/*
+		Resolution is the width of each frequency bin, in Hz.
+	*/
+	Resolution float64
+	/*
+		FrameRate is the number of frames per second.
+	*/
+	FrameRate float64
+	/*
+		Options stores the options used to compute the spectrogram, with defaults filled in.
+	*/
+	Options SpectrogramOptions
+}
+
+/*
+ComputeSpectrogram computes the spectrogram of a Song (downmixed to mono), using
+Hann-windowed frames.
+
+If the Song has no samples, ComputeSpectrogram returns ErrNoSamples.
+*/
+func ComputeSpectrogram(song *Song, options SpectrogramOptions) (*Spectrogram, error) {
+	if options.FFTSize == 0 {
+		options.FFTSize = defaultSpectrogramSize
+	}
+	if options.Hop == 0 {
+		options.Hop = options.FFTSize / 4
+		if options.Hop < 1 {
+			options.Hop = 1
+		}
+	}
+	if options.ColorMap == nil {
+		options.ColorMap = HeatColorMap
+	}
+	if options.Floor == 0 {
+		options.Floor = defaultSpectrogramFloor
+	}
+	if options.FFTSize < 2 || nextPowerOfTwo(options.FFTSize) != options.FFTSize {
+		return nil, errors.New("bliss: FFT size must be a power of two")
+	}
+	if options.Hop <= 0 {
+		return nil, errors.New("bliss: invalid spectrogram hop")
+	}
+	if options.Floor > 0 {
+		return nil, errors.New("bliss: spectrogram floor must be negative")
+	}
+	mono, err := song.monoSamples()
+	if err != nil {
+		return nil, err
+	}
+	// the peak magnitude of a full-scale sine through a Hann window
+	fullScale := float64(options.FFTSize) / 4
+	spectrogram := &Spectrogram{
+		Resolution: float64(song.SampleRate) / float64(options.FFTSize),
+		FrameRate:  float64(song.SampleRate) / float64(options.Hop),
+		Options:    options,
+	}
+	forEachSpectrum(mono, options.FFTSize, options.Hop, func(frame int, power []float64) {
+		magnitudes := make([]float64, len(power))
+		for i, p := range power {
+			magnitudes[i] = decibels(p / (fullScale * fullScale))
+		}
+		spectrogram.Magnitudes = append(spectrogram.Magnitudes, magnitudes)
+	})
+	return spectrogram, nil
+}
+
+/*
+Image renders the spectrogram with its ColorMap, with one pixel per frame and per
+frequency bin: time goes from left to right, and frequency from bottom to top.
+*/
+func (spectrogram *Spectrogram) Image() *image.RGBA {
+	bins := spectrogram.Options.FFTSize/2 + 1
+	img := image.NewRGBA(image.Rect(0, 0, len(spectrogram.Magnitudes), bins))
+	floor := spectrogram.Options.Floor
+	for x, magnitudes := range spectrogram.Magnitudes {
+		for i, m := range magnitudes {
+			img.Set(x, bins-1-i, spectrogram.Options.ColorMap((m-floor)/-floor))
+		}
+	}
+	return img
+}
+
+/*
+WritePNG renders the spectrogram as with Image, and writes it as a PNG image to w.
+*/
+func (spectrogram *Spectrogram) WritePNG(w io.Writer) error {
+	return png.Encode(w, spectrogram.Image())
+}
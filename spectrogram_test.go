@@ -0,0 +1,65 @@
+package bliss
+
+import (
+	"bytes"
+	"image/png"
+	"math"
+	"testing"
+)
+
+func TestComputeSpectrogram(t *testing.T) {
+	const rate = 22050
+	samples := sine(rate, rate, 1000, 0.5)
+	spectrogram, err := ComputeSpectrogram(newTestSong(rate, 1, samples), SpectrogramOptions{
+		FFTSize: 1024,
+		Hop:     512,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, (rate-1024)/512+1, len(spectrogram.Magnitudes), "frames")
+	assertInt(t, 513, len(spectrogram.Magnitudes[0]), "bins")
+	assertNear(t, float64(rate)/1024, spectrogram.Resolution, 1e-9, "resolution")
+
+	frame := spectrogram.Magnitudes[10]
+	peak := 0
+	for i, m := range frame {
+		if m > frame[peak] {
+			peak = i
+		}
+	}
+	assertNear(t, 1000, float64(peak)*spectrogram.Resolution, spectrogram.Resolution, "peak frequency")
+	// the sine energy is spread over neighbouring bins
+	assertNear(t, 20*math.Log10(0.5), frame[peak], 2, "peak level")
+
+	var buffer bytes.Buffer
+	if err := spectrogram.WritePNG(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, len(spectrogram.Magnitudes), img.Bounds().Dx(), "image width")
+	assertInt(t, 513, img.Bounds().Dy(), "image height")
+	// the peak is brighter than the background, low frequencies at the bottom
+	r, _, _, _ := img.At(10, 512-peak).RGBA()
+	background, _, _, _ := img.At(10, 0).RGBA()
+	if r <= background {
+		t.Errorf("expected the peak to be brighter than the background")
+	}
+
+	if _, err := ComputeSpectrogram(newTestSong(rate, 1, samples), SpectrogramOptions{FFTSize: 1000}); err == nil {
+		t.Error("expected an error for a FFT size not a power of two")
+	}
+
+	tiny, err := ComputeSpectrogram(newTestSong(rate, 1, samples[:64]), SpectrogramOptions{FFTSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, 1, tiny.Options.Hop, "default hop of a tiny FFT size")
+	assertInt(t, 63, len(tiny.Magnitudes), "frames of a tiny FFT size")
+	if _, err := ComputeSpectrogram(newTestSong(rate, 1, samples), SpectrogramOptions{Hop: -1}); err == nil {
+		t.Error("expected an error for a negative hop")
+	}
+}
@@ -0,0 +1,97 @@
+package bliss
+
+import (
+	"errors"
+	"math"
+)
+
+// dualMonoThreshold is the mid/side energy ratio, in dB, under which a song is dual mono.
+const dualMonoThreshold = -50
+
+/*
+Stereo stores how a stereo Song uses the stereo field.
+*/
+type Stereo struct {
+	/*
+		Correlation is the correlation between the left and right channels, between
+		-1 (opposite phase) and 1 (identical channels). Negative values hint at phase issues.
+	*/
+	Correlation float64
+	/*
+		MidSideRatio is the energy of the side signal (L-R) relative to the energy of the
+		mid signal (L+R), in dB. It is -Inf for identical channels (including silent
+		channels), and +Inf for channels in opposite phase.
+	*/
+	MidSideRatio float64
+	/*
+		Width is the share of the side signal in the total energy, between 0 (mono)
+		and 1 (channels in opposite phase).
+	*/
+	Width float64
+	/*
+		Balance is the energy of the left channel relative to the right channel, in dB.
+		It is 0 if both channels are silent, and -Inf or +Inf if only the left or the
+		right channel is silent.
+	*/
+	Balance float64
+	/*
+		DualMono is true if both channels are (nearly) identical, i.e. the song is
+		mono stored as stereo.
+	*/
+	DualMono bool
+}
+
+/*
+FeatureVector returns the stereo measures as a FeatureVector, with the dimensions
+"stereo_correlation" and "stereo_width", e.g. to extend the features of ExtractFeatures.
+*/
+func (stereo *Stereo) FeatureVector() FeatureVector {
+	return FeatureVector{
+		Names:  []string{"stereo_correlation", "stereo_width"},
+		Values: []float64{stereo.Correlation, stereo.Width},
+	}
+}
+
+/*
+AnalyzeStereo measures how a stereo Song uses the stereo field: inter-channel correlation,
+mid/side energy ratio, width, balance, and whether it is dual mono.
+
+If the Song is not stereo, AnalyzeStereo returns an error.
+If the Song has no samples, AnalyzeStereo returns ErrNoSamples.
+*/
+func AnalyzeStereo(song *Song) (*Stereo, error) {
+	channels, err := song.channelSamples()
+	if err != nil {
+		return nil, err
+	}
+	if len(channels) != 2 {
+		return nil, errors.New("bliss: song is not stereo")
+	}
+	var left, right, cross, mid, side float64
+	for i, l := range channels[0] {
+		r := channels[1][i]
+		left += l * l
+		right += r * r
+		cross += l * r
+		m, s := (l+r)/2, (l-r)/2
+		mid += m * m
+		side += s * s
+	}
+	stereo := &Stereo{
+		MidSideRatio: math.Inf(-1),
+	}
+	if side > 0 {
+		stereo.MidSideRatio = 10 * math.Log10(side/mid)
+	}
+	if left > 0 || right > 0 {
+		stereo.Balance = 10 * math.Log10(left/right)
+	}
+	stereo.DualMono = side == 0 || stereo.MidSideRatio < dualMonoThreshold
+	if left > 0 && right > 0 {
+		stereo.Correlation = cross / math.Sqrt(left*right)
+	}
+	if mid+side > 0 {
+		stereo.Width = side / (mid + side)
+	}
+	return stereo, nil
+}
@@ -0,0 +1,66 @@
+package bliss
+
+import (
+	"math"
+	"testing"
+)
+
+func interleave(left []float64, right []float64) []float64 {
+	samples := make([]float64, 2*len(left))
+	for i := range left {
+		samples[2*i] = left[i]
+		samples[2*i+1] = right[i]
+	}
+	return samples
+}
+
+func TestAnalyzeStereo(t *testing.T) {
+	const rate = 22050
+	tone := sine(rate, rate, 440, 0.5)
+
+	stereo, err := AnalyzeStereo(newTestSong(rate, 2, interleave(tone, tone)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stereo.DualMono {
+		t.Error("expected identical channels to be dual mono")
+	}
+	assertNear(t, 1, stereo.Correlation, 1e-9, "dual mono correlation")
+	assertNear(t, 0, stereo.Width, 1e-9, "dual mono width")
+	assertNear(t, 0, stereo.Balance, 1e-9, "dual mono balance")
+
+	other := sine(rate, rate, 554.37, 0.25)
+	stereo, err = AnalyzeStereo(newTestSong(rate, 2, interleave(tone, other)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stereo.DualMono {
+		t.Error("expected different channels not to be dual mono")
+	}
+	assertNear(t, 0, stereo.Correlation, 0.05, "uncorrelated channels correlation")
+	assertNear(t, 20*math.Log10(2), stereo.Balance, 0.05, "balance")
+
+	inverted := make([]float64, len(tone))
+	for i, v := range tone {
+		inverted[i] = -v
+	}
+	stereo, err = AnalyzeStereo(newTestSong(rate, 2, interleave(tone, inverted)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNear(t, -1, stereo.Correlation, 1e-3, "opposite phase correlation")
+	assertNear(t, 1, stereo.Width, 1e-3, "opposite phase width")
+
+	silence := make([]float64, len(tone))
+	stereo, err = AnalyzeStereo(newTestSong(rate, 2, interleave(silence, silence)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stereo.DualMono || !math.IsInf(stereo.MidSideRatio, -1) || stereo.Balance != 0 || stereo.Correlation != 0 || stereo.Width != 0 {
+		t.Errorf("unexpected silent stereo measures: %+v", stereo)
+	}
+
+	if _, err := AnalyzeStereo(newTestSong(rate, 1, tone)); err == nil {
+		t.Error("expected an error analyzing a mono song")
+	}
+}
@@ -0,0 +1,370 @@
+package bliss
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+const (
+	// defaultStructureStep is the default duration of the steps of self-similarity matrices.
+	defaultStructureStep = time.Second
+	// defaultStructureKernel is the default half-width of the novelty kernel.
+	defaultStructureKernel = 8 * time.Second
+	// defaultMinSection is the default minimum length of sections.
+	defaultMinSection = 8 * time.Second
+	// sectionSimilarity is the ratio of the inner similarity of two sections above which
+	// their similarity makes them repetitions of the same section.
+	sectionSimilarity = 0.9
+)
+
+/*
+StructureOptions stores the options of AnalyzeStructure.
+*/
+type StructureOptions struct {
+	/*
+		Step is the duration of each row and column of the self-similarity matrix.
+		Defaults to 1 second.
+	*/
+	Step time.Duration
+	/*
+		Kernel is the half-width of the checkerboard kernel used to compute the novelty curve:
+		changes are detected by comparing the Kernel before and after each step.
+		Defaults to 8 seconds.
+	*/
+	Kernel time.Duration
+	/*
+		MinSection is the minimum length of sections. Defaults to 8 seconds.
+	*/
+	MinSection time.Duration
+}
+
+/*
+SelfSimilarity is the self-similarity matrix of a Song: the similarity between the
+features of each pair of steps of the song.
+*/
+type SelfSimilarity struct {
+	/*
+		Step is the duration of each row and column of the matrix.
+	*/
+	Step time.Duration
+	/*
+		Matrix stores the similarity between each pair of steps, between -1 and 1. It is
+		symmetric, and Matrix[i][i] is 1 for non-silent steps.
+	*/
+	Matrix [][]float64
+}
+
+/*
+Section is a section of a Song, e.g. an intro or a chorus.
+*/
+type Section struct {
+	/*
+		Start is the start of the section, from the start of the song.
+	*/
+	Start time.Duration
+	/*
+		End is the end of the section, from the start of the song.
+	*/
+	End time.Duration
+	/*
+		Group is the index of the group of the section: repetitions of a section (e.g.
+		choruses) have the same Group. Groups are numbered in order of appearance from 0.
+	*/
+	Group int
+	/*
+		Label is the label of the section: "intro" and "outro" for the first and last
+		sections if they are not repeated, or a letter for its Group otherwise ("A", "B", ...,
+		in order of appearance).
+	*/
+	Label string
+	/*
+		ForceVector stores the ratings of the section, like Song.ForceVector.
+	*/
+	ForceVector ForceVector
+}
+
+/*
+Structure stores the structural analysis of a Song.
+*/
+type Structure struct {
+	/*
+		SelfSimilarity is the self-similarity matrix of the song.
+	*/
+	SelfSimilarity SelfSimilarity
+	/*
+		Novelty is the novelty curve of the song, between 0 and 1: how much the song
+		changes at each step of SelfSimilarity.
+	*/
+	Novelty []float64
+	/*
+		Sections stores the sections of the song, in order. They cover the whole song.
+	*/
+	Sections []Section
+}
+
+/*
+ComputeSelfSimilarity computes the self-similarity matrix of a Song, with steps of
+the given duration.
+
+The features of each step are its timbre (the mel-frequency cepstral coefficients of
+ExtractFeatures) and its harmony (the chroma features of ExtractFeatures); the
+similarity of two steps is the mean of the cosine similarities of their features.
+
+If the Song has no samples, ComputeSelfSimilarity returns ErrNoSamples.
+*/
+func ComputeSelfSimilarity(song *Song, step time.Duration) (*SelfSimilarity, error) {
+	if step <= 0 {
+		return nil, errors.New("bliss: step must be positive")
+	}
+	mono, err := song.monoSamples()
+	if err != nil {
+		return nil, err
+	}
+	features := computeFrameFeatures(mono, song.SampleRate)
+	steps, indices := frameSteps(len(features), len(mono), song.SampleRate, step)
+
+	// the first coefficient is the loudness rather than the timbre
+	timbre := make([][mfccCoefficients - 1]float64, steps)
+	chroma := make([][12]float64, steps)
+	counts := make([]int, steps)
+	for i, f := range features {
+		s := indices[i]
+		for c := range timbre[s] {
+			timbre[s][c] += f.mfcc[c+1]
+		}
+		for c, v := range f.chroma {
+			chroma[s][c] += v
+		}
+		counts[s]++
+	}
+	var mean [mfccCoefficients - 1]float64
+	audible := 0
+	for s := range timbre {
+		if counts[s] == 0 {
+			continue
+		}
+		for c := range timbre[s] {
+			timbre[s][c] /= float64(counts[s])
+			mean[c] += timbre[s][c]
+		}
+		audible++
+	}
+	// center timbre features, so that their cosine similarity is meaningful
+	for s := range timbre {
+		if counts[s] > 0 {
+			for c := range timbre[s] {
+				timbre[s][c] -= mean[c] / float64(audible)
+			}
+		}
+		normalize(timbre[s][:])
+		normalize(chroma[s][:])
+	}
+
+	matrix := make([][]float64, steps)
+	for i := range matrix {
+		matrix[i] = make([]float64, steps)
+	}
+	for i := range matrix {
+		for j := i; j < steps; j++ {
+			var t float64
+			for c := range timbre[i] {
+				t += timbre[i][c] * timbre[j][c]
+			}
+			similarity := (t + chromaSimilarity(&chroma[i], &chroma[j])) / 2
+			matrix[i][j] = similarity
+			matrix[j][i] = similarity
+		}
+	}
+	return &SelfSimilarity{
+		Step:   step,
+		Matrix: matrix,
+	}, nil
+}
+
+// novelty computes the novelty curve of a self-similarity matrix by correlating its
+// diagonal with a gaussian-tapered checkerboard kernel of half-width kernel steps.
+func novelty(matrix [][]float64, kernel int) []float64 {
+	curve := make([]float64, len(matrix))
+	sigma := float64(kernel) / 2
+	var max float64
+	for i := range matrix {
+		var sum float64
+		for a := -kernel; a < kernel; a++ {
+			for b := -kernel; b < kernel; b++ {
+				x, y := i+a, i+b
+				if x < 0 || y < 0 || x >= len(matrix) || y >= len(matrix) {
+					continue
+				}
+				// offsets are centered between i-1 and i
+				da, db := float64(a)+0.5, float64(b)+0.5
+				weight := math.Exp(-(da*da + db*db) / (2 * sigma * sigma))
+				if (a < 0) != (b < 0) {
+					weight = -weight
+				}
+				sum += weight * matrix[x][y]
+			}
+		}
+		curve[i] = math.Max(0, sum)
+		max = math.Max(max, curve[i])
+	}
+	if max > 0 {
+		for i := range curve {
+			curve[i] /= max
+		}
+	}
+	return curve
+}
+
+// boundaries returns the steps of the peaks of the novelty curve above its mean, at
+// least distance steps apart and from the ends of the curve.
+func boundaries(curve []float64, distance int) []int {
+	var mean float64
+	for _, v := range curve {
+		mean += v
+	}
+	mean /= float64(len(curve))
+
+	var peaks []int
+	for i := distance; i <= len(curve)-distance; i++ {
+		if curve[i] <= mean {
+			continue
+		}
+		peak := true
+		for j := i - distance + 1; j < i+distance && j < len(curve); j++ {
+			if curve[j] > curve[i] || (curve[j] == curve[i] && j < i) {
+				peak = false
+				break
+			}
+		}
+		if peak {
+			peaks = append(peaks, i)
+		}
+	}
+	return peaks
+}
+
+// blockSimilarity returns the mean similarity between the steps of two ranges.
+func blockSimilarity(matrix [][]float64, a Interval, b Interval, step time.Duration) float64 {
+	var sum float64
+	n := 0
+	for i := int(a.Start / step); i < int(a.End/step) && i < len(matrix); i++ {
+		for j := int(b.Start / step); j < int(b.End/step) && j < len(matrix); j++ {
+			sum += matrix[i][j]
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+/*
+AnalyzeStructure segments a Song into sections, e.g. to analyze and compare them
+separately.
+
+Section boundaries are the peaks of the novelty curve of the self-similarity matrix
+of the song (see ComputeSelfSimilarity); sections similar to each other are then
+grouped and labelled.
+
+If the Song has no samples, AnalyzeStructure returns ErrNoSamples.
+*/
+func AnalyzeStructure(song *Song, options StructureOptions) (*Structure, error) {
+	step := options.Step
+	if step == 0 {
+		step = defaultStructureStep
+	}
+	kernel := options.Kernel
+	if kernel == 0 {
+		kernel = defaultStructureKernel
+	}
+	minSection := options.MinSection
+	if minSection == 0 {
+		minSection = defaultMinSection
+	}
+	selfSimilarity, err := ComputeSelfSimilarity(song, step)
+	if err != nil {
+		return nil, err
+	}
+	structure := &Structure{
+		SelfSimilarity: *selfSimilarity,
+		Novelty:        novelty(selfSimilarity.Matrix, int(math.Ceil(float64(kernel)/float64(step)))),
+	}
+
+	distance := int(math.Ceil(float64(minSection) / float64(step)))
+	if distance < 1 {
+		distance = 1
+	}
+	length := song.length()
+	start := time.Duration(0)
+	for _, boundary := range append(boundaries(structure.Novelty, distance), -1) {
+		end := length
+		if boundary >= 0 {
+			end = time.Duration(boundary) * step
+		}
+		if end <= start {
+			continue
+		}
+		structure.Sections = append(structure.Sections, Section{
+			Start: start,
+			End:   end,
+		})
+		start = end
+	}
+
+	matrix := selfSimilarity.Matrix
+	var groups []Interval
+	var counts []int
+	for i := range structure.Sections {
+		section := &structure.Sections[i]
+		interval := Interval{Start: section.Start, End: section.End}
+		inner := blockSimilarity(matrix, interval, interval, step)
+		section.Group = -1
+		for g, group := range groups {
+			threshold := sectionSimilarity * math.Min(inner, blockSimilarity(matrix, group, group, step))
+			if blockSimilarity(matrix, interval, group, step) >= threshold {
+				section.Group = g
+				break
+			}
+		}
+		if section.Group < 0 {
+			section.Group = len(groups)
+			groups = append(groups, interval)
+			counts = append(counts, 0)
+		}
+		counts[section.Group]++
+
+		analysis, err := AnalyzeRange(song, section.Start, section.End)
+		if err != nil {
+			return nil, err
+		}
+		section.ForceVector = analysis.ForceVector
+	}
+
+	letters := make(map[int]string)
+	for i := range structure.Sections {
+		section := &structure.Sections[i]
+		switch {
+		case len(structure.Sections) > 1 && i == 0 && counts[section.Group] == 1:
+			section.Label = "intro"
+		case len(structure.Sections) > 1 && i == len(structure.Sections)-1 && counts[section.Group] == 1:
+			section.Label = "outro"
+		default:
+			if _, ok := letters[section.Group]; !ok {
+				letters[section.Group] = groupLabel(len(letters))
+			}
+			section.Label = letters[section.Group]
+		}
+	}
+	return structure, nil
+}
+
+// groupLabel returns the letter label of a group: "A" to "Z", then "AA", "AB", ...
+func groupLabel(group int) string {
+	var label []byte
+	for ; group >= 0; group = group/26 - 1 {
+		label = append([]byte{byte('A' + group%26)}, label...)
+	}
+	return string(label)
+}
@@ -0,0 +1,57 @@
+package bliss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnalyzeStructure(t *testing.T) {
+	const rate = 22050
+	section := func(frequencies ...float64) []float64 {
+		samples := make([]float64, 10*rate)
+		for _, frequency := range frequencies {
+			for i, v := range sine(rate, len(samples), frequency, 0.2) {
+				samples[i] += v
+			}
+		}
+		return samples
+	}
+	// intro, chorus, verse, chorus, outro
+	var samples []float64
+	samples = append(samples, section(196)...)
+	samples = append(samples, section(440, 554.37)...)
+	samples = append(samples, section(329.63)...)
+	samples = append(samples, section(440, 554.37)...)
+	samples = append(samples, section(369.99, 2000)...)
+
+	structure, err := AnalyzeStructure(newTestSong(rate, 1, samples), StructureOptions{
+		Kernel:     4 * time.Second,
+		MinSection: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, 50, len(structure.SelfSimilarity.Matrix), "matrix size")
+	assertNear(t, 1, structure.SelfSimilarity.Matrix[15][35], 0.05, "chorus similarity")
+
+	labels := []string{"intro", "A", "B", "A", "outro"}
+	if len(structure.Sections) != len(labels) {
+		t.Fatalf("expected %d sections, got: %+v", len(labels), structure.Sections)
+	}
+	for i, section := range structure.Sections {
+		assertNear(t, float64(10*i), section.Start.Seconds(), 1, "section start")
+		assertNear(t, float64(10*(i+1)), section.End.Seconds(), 1, "section end")
+		if section.Label != labels[i] {
+			t.Errorf("section %d: expected label %q, got: %q", i, labels[i], section.Label)
+		}
+	}
+	assertInt(t, structure.Sections[1].Group, structure.Sections[3].Group, "chorus group")
+}
+
+func TestGroupLabel(t *testing.T) {
+	for group, label := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 52: "BA"} {
+		if l := groupLabel(group); l != label {
+			t.Errorf("group %d: expected label %q, got: %q", group, label, l)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package bliss
+
+import (
+	"sort"
+)
+
+// defaultTagPercentile is the default percentile of the thresholds of FitTagger.
+const defaultTagPercentile = 25
+
+/*
+Tag is a human-readable descriptor of a song, e.g. for search facets. Tags are stable
+strings that can be stored.
+*/
+type Tag string
+
+const (
+	/*
+		TagFast is the tag of songs with a high tempo rating.
+	*/
+	TagFast Tag = "fast"
+	/*
+		TagSlow is the tag of songs with a low tempo rating.
+	*/
+	TagSlow Tag = "slow"
+	/*
+		TagPunchy is the tag of songs with a high attack rating.
+	*/
+	TagPunchy Tag = "punchy"
+	/*
+		TagSmooth is the tag of songs with a low attack rating.
+	*/
+	TagSmooth Tag = "smooth"
+	/*
+		TagLoud is the tag of songs with a high amplitude rating.
+	*/
+	TagLoud Tag = "loud"
+	/*
+		TagQuiet is the tag of songs with a low amplitude rating.
+	*/
+	TagQuiet Tag = "quiet"
+	/*
+		TagBright is the tag of songs with a high frequency rating.
+	*/
+	TagBright Tag = "bright"
+	/*
+		TagDark is the tag of songs with a low frequency rating.
+	*/
+	TagDark Tag = "dark"
+)
+
+/*
+TagThreshold stores the thresholds of the tags of a dimension of force vectors.
+*/
+type TagThreshold struct {
+	/*
+		Dimension is the name of the dimension: "tempo", "attack", "amplitude" or "frequency",
+		as in ForceVector.FeatureVector.
+	*/
+	Dimension string
+	/*
+		Low is the rating at or under which songs get the low tag of the dimension
+		(TagSlow, TagSmooth, TagQuiet or TagDark).
+	*/
+	Low float32
+	/*
+		High is the rating at or above which songs get the high tag of the dimension
+		(TagFast, TagPunchy, TagLoud or TagBright).
+	*/
+	High float32
+}
+
+/*
+Tagger maps force vectors to tags.
+
+A Tagger can be serialized, e.g. with encoding/json, so that the tags of a library are
+computed with the same thresholds over time.
+*/
+type Tagger struct {
+	/*
+		Thresholds stores the thresholds of each dimension. Dimensions without thresholds
+		are not tagged.
+	*/
+	Thresholds []TagThreshold
+}
+
+/*
+FitTagger returns a Tagger whose thresholds are percentiles of the ratings of a library:
+for each dimension, songs under the given percentile get the low tag, and songs above
+100 minus the given percentile get the high tag.
+
+percentile must be between 0 and 50; if it is 0, it defaults to 25, so that each tag
+is given to a quarter of the library.
+
+FitTagger panics if there are no vectors, or if percentile is not between 0 and 50.
+*/
+func FitTagger(forceVectors []ForceVector, percentile float64) *Tagger {
+	if len(forceVectors) == 0 {
+		panic("bliss: no force vectors to fit")
+	}
+	if !(percentile >= 0 && percentile <= 50) {
+		panic("bliss: tag percentile must be between 0 and 50")
+	}
+	if percentile == 0 {
+		percentile = defaultTagPercentile
+	}
+	tagger := &Tagger{
+		Thresholds: make([]TagThreshold, len(forceDimensions)),
+	}
+	values := make([]float64, len(forceVectors))
+	for i := range forceDimensions {
+		dimension := &forceDimensions[i]
+		for j, forceVector := range forceVectors {
+			values[j] = float64(dimension.value(forceVector))
+		}
+		sort.Float64s(values)
+		tagger.Thresholds[i] = TagThreshold{
+			Dimension: dimension.name,
+			Low:       float32(quantile(values, percentile/100)),
+			High:      float32(quantile(values, 1-percentile/100)),
+		}
+	}
+	return tagger
+}
+
+/*
+Tags returns the tags of a force vector, in the order of the Thresholds of the Tagger.
+*/
+func (tagger *Tagger) Tags(forceVector ForceVector) []Tag {
+	var tags []Tag
+	for _, threshold := range tagger.Thresholds {
+		for i := range forceDimensions {
+			dimension := &forceDimensions[i]
+			if dimension.name != threshold.Dimension {
+				continue
+			}
+			value := dimension.value(forceVector)
+			// ratings equal to both thresholds (e.g. if all fitted ratings are equal) are not tagged
+			if value <= threshold.Low && value < threshold.High {
+				tags = append(tags, dimension.low)
+			} else if value >= threshold.High && value > threshold.Low {
+				tags = append(tags, dimension.high)
+			}
+		}
+	}
+	return tags
+}
@@ -0,0 +1,54 @@
+package bliss
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTagger(t *testing.T) {
+	var forceVectors []ForceVector
+	for i := 0; i <= 100; i++ {
+		forceVectors = append(forceVectors, ForceVector{
+			Tempo:     float32(i),
+			Attack:    float32(-i),
+			Amplitude: 10,
+			Frequency: float32(i % 10),
+		})
+	}
+	tagger := FitTagger(forceVectors, 0)
+	assertString(t, "tempo", tagger.Thresholds[0].Dimension, "first dimension")
+	assertFloat(t, 25, tagger.Thresholds[0].Low, "tempo low threshold")
+	assertFloat(t, 75, tagger.Thresholds[0].High, "tempo high threshold")
+
+	tags := tagger.Tags(ForceVector{Tempo: 90, Attack: -50, Amplitude: 10, Frequency: 1})
+	if !reflect.DeepEqual(tags, []Tag{TagFast, TagDark}) {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+
+	encoded, err := json.Marshal(tagger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Tagger
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tagger, &decoded) {
+		t.Errorf("unexpected decoded tagger: %+v", decoded)
+	}
+}
+
+func TestFitTaggerInvalidPercentile(t *testing.T) {
+	for _, percentile := range []float64{-1, 51, math.NaN()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected FitTagger to panic with percentile %f", percentile)
+				}
+			}()
+			FitTagger([]ForceVector{{}}, percentile)
+		}()
+	}
+}
@@ -0,0 +1,171 @@
+package bliss
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// minTempo and maxTempo bound the estimated tempo, in beats per minute.
+	minTempo = 40
+	maxTempo = 240
+	// preferredTempo is the center of the tempo prior, in beats per minute.
+	preferredTempo = 120
+)
+
+/*
+TempoCandidate is a possible tempo of a Song.
+*/
+type TempoCandidate struct {
+	/*
+		BPM is the tempo in beats per minute.
+	*/
+	BPM float64
+	/*
+		Score is the normalized autocorrelation of the song onsets at the beat period,
+		between -1 and 1. Higher values mean a stronger periodicity.
+	*/
+	Score float64
+}
+
+/*
+TempoEstimate is the estimated tempo of a Song, in beats per minute.
+*/
+type TempoEstimate struct {
+	/*
+		BPM is the most likely tempo in beats per minute.
+	*/
+	BPM float64
+	/*
+		Confidence is how periodic the song is at BPM, between 0 and 1.
+		Songs without a steady beat have a low confidence.
+	*/
+	Confidence float64
+	/*
+		Candidates are BPM and its half and double tempos that are between 40 and 240 BPM,
+		by decreasing Score.
+	*/
+	Candidates []TempoCandidate
+}
+
+// onsetStrength returns the positive part of the derivative of the envelope,
+// the song being silent before its start.
+func onsetStrength(envelope []float64) []float64 {
+	onsets := make([]float64, len(envelope))
+	if len(envelope) > 0 {
+		onsets[0] = envelope[0]
+	}
+	for i := 1; i < len(envelope); i++ {
+		if d := envelope[i] - envelope[i-1]; d > 0 {
+			onsets[i] = d
+		}
+	}
+	return onsets
+}
+
+// autocorrelation returns the normalized autocorrelation of x (with its mean removed) at lag.
+func autocorrelation(x []float64, mean float64, energy float64, lag float64) float64 {
+	if energy == 0 {
+		return 0
+	}
+	// linear interpolation for fractional lags
+	l := int(lag)
+	f := lag - float64(l)
+	var sum float64
+	for i := 0; i+l+1 < len(x); i++ {
+		shifted := (1-f)*x[i+l] + f*x[i+l+1]
+		sum += (x[i] - mean) * (shifted - mean)
+	}
+	return sum / energy
+}
+
+/*
+EstimateTempo estimates the tempo of a Song in beats per minute.
+
+Unlike the tempo rating of ForceVector, the estimate is an actual tempo. It is
+computed from the envelope returned by AnalyzeEnvelope: the tempo is the period
+maximizing the autocorrelation of the song onsets, with a preference for tempos
+around 120 BPM. Only tempos between 40 and 240 BPM are considered.
+
+If the Song is silent or too short to contain a beat, the estimate is zero.
+
+If the Song has no samples, EstimateTempo returns ErrNoSamples.
+*/
+func EstimateTempo(song *Song) (*TempoEstimate, error) {
+	mono, err := song.monoSamples()
+	if err != nil {
+		return nil, err
+	}
+	rate := float64(song.SampleRate) / float64(envelopeHop(song.SampleRate))
+	onsets := onsetStrength(envelopeOf(mono, song.SampleRate))
+	return estimateTempo(onsets, rate), nil
+}
+
+func estimateTempo(onsets []float64, rate float64) *TempoEstimate {
+	// beats per minute to envelope points per beat, and back
+	perMinute := 60 * rate
+	minLag := int(perMinute / maxTempo)
+	if len(onsets) <= minLag {
+		return &TempoEstimate{}
+	}
+	var mean, energy float64
+	for _, v := range onsets {
+		mean += v
+	}
+	mean /= float64(len(onsets))
+	for _, v := range onsets {
+		energy += (v - mean) * (v - mean)
+	}
+	if energy == 0 {
+		return &TempoEstimate{}
+	}
+	score := func(bpm float64) float64 {
+		return autocorrelation(onsets, mean, energy, perMinute/bpm)
+	}
+
+	var best float64
+	bestWeighted := math.Inf(-1)
+	for lag := minLag; lag <= int(math.Ceil(perMinute/minTempo)); lag++ {
+		bpm := perMinute / float64(lag)
+		if bpm < minTempo || bpm > maxTempo {
+			continue
+		}
+		// log-gaussian prior around the preferred tempo
+		octaves := math.Log2(bpm / preferredTempo)
+		weighted := score(bpm) * math.Exp(-0.5*octaves*octaves)
+		if weighted > bestWeighted {
+			bestWeighted = weighted
+			best = float64(lag)
+		}
+	}
+	if best == 0 {
+		return &TempoEstimate{}
+	}
+
+	// refine the lag with a parabolic interpolation
+	previous, current, next := score(perMinute/(best-1)), score(perMinute/best), score(perMinute/(best+1))
+	if d := previous - 2*current + next; d < 0 {
+		if offset := 0.5 * (previous - next) / d; math.Abs(offset) < 1 {
+			best += offset
+		}
+	}
+	bpm := perMinute / best
+
+	estimate := &TempoEstimate{
+		BPM:        bpm,
+		Confidence: math.Max(0, math.Min(1, score(bpm))),
+	}
+	for _, candidate := range []float64{bpm, bpm / 2, bpm * 2} {
+		if candidate < minTempo || candidate > maxTempo {
+			continue
+		}
+		estimate.Candidates = append(estimate.Candidates, TempoCandidate{
+			BPM:   candidate,
+			Score: score(candidate),
+		})
+	}
+	sort.SliceStable(estimate.Candidates, func(i, j int) bool {
+		return estimate.Candidates[i].Score > estimate.Candidates[j].Score
+	})
+	return estimate
+}
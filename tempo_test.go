@@ -0,0 +1,53 @@
+package bliss
+
+import (
+	"math"
+	"testing"
+)
+
+// clicks returns n samples of 1 kHz clicks, 50 ms long, at the given tempo.
+func clicks(sampleRate int, n int, bpm float64) []float64 {
+	samples := sine(sampleRate, n, 1000, 0.8)
+	period := int(60 / bpm * float64(sampleRate))
+	for i := range samples {
+		if i%period > sampleRate/20 {
+			samples[i] = 0
+		}
+	}
+	return samples
+}
+
+func TestEstimateTempo(t *testing.T) {
+	const rate = 22050
+	song := newTestSong(rate, 1, clicks(rate, 20*rate, 128))
+	tempo, err := EstimateTempo(song)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(tempo.BPM-128) > 2 {
+		t.Errorf("expected a tempo of 128 BPM, got: %f", tempo.BPM)
+	}
+	if tempo.Confidence < 0.5 {
+		t.Errorf("expected a high confidence, got: %f", tempo.Confidence)
+	}
+	// the double tempo is above 240 BPM
+	assertInt(t, 2, len(tempo.Candidates), "tempo candidates count")
+	for _, candidate := range tempo.Candidates {
+		if candidate.BPM < minTempo || candidate.BPM > maxTempo {
+			t.Errorf("unexpected tempo candidate: %f", candidate.BPM)
+		}
+	}
+}
+
+func TestEstimateTempoSilence(t *testing.T) {
+	const rate = 22050
+	for _, n := range []int{5 * rate, rate / 200} {
+		tempo, err := EstimateTempo(newTestSong(rate, 1, make([]float64, n)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tempo.BPM != 0 || tempo.Confidence != 0 || len(tempo.Candidates) != 0 {
+			t.Errorf("expected no tempo for %d silent samples, got: %+v", n, tempo)
+		}
+	}
+}
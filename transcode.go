@@ -0,0 +1,108 @@
+package bliss
+
+import (
+	"math"
+)
+
+const (
+	// transcodeReferenceLow and transcodeReferenceHigh bound the band whose level is
+	// the reference of the spectrum, in Hz.
+	transcodeReferenceLow  = 1000
+	transcodeReferenceHigh = 5000
+	// cutoffDepth is the level under the reference, in dB, above which frequencies are in the bandwidth.
+	cutoffDepth = 30
+	// cliffMargin and cliffWidth, in Hz, delimit the bands compared on each side of the cutoff.
+	cliffMargin = 300
+	cliffWidth  = 1000
+	// cliffDrop is the minimum level drop at the cutoff, in dB, of lossy-sourced songs.
+	cliffDrop = 20
+	// minTranscodeNyquist is the minimum Nyquist frequency, in Hz, for which lossy
+	// encoders cut off frequencies audibly below it.
+	minTranscodeNyquist = 15000
+)
+
+/*
+Transcode stores the estimated bandwidth of a Song, and whether the Song is likely
+sourced from a lossy file, e.g. a FLAC file transcoded from a low-bitrate MP3.
+
+Lossy encoders remove high frequencies, producing a sharp drop (a "cliff") in the
+spectrum, typically around 16 kHz, while genuine lossless recordings roll off gradually.
+*/
+type Transcode struct {
+	/*
+		Cutoff is the effective bandwidth of the song, in Hz: the highest frequency
+		whose level is within 30 dB of the level of the 1 to 5 kHz band.
+	*/
+	Cutoff float64
+	/*
+		Drop is the level drop at Cutoff, in dB, between the bands below and above it.
+	*/
+	Drop float64
+	/*
+		Lossy is true if the song is likely sourced from a lossy file.
+	*/
+	Lossy bool
+	/*
+		Confidence is the confidence that the song is sourced from a lossy file,
+		between 0 and 1.
+	*/
+	Confidence float64
+}
+
+/*
+DetectTranscode estimates the effective bandwidth of a Song from its mean spectrum
+(as computed by AnalyzeSpectrum), and flags songs likely sourced from lossy files.
+
+Songs whose sampling rate is lower than 30 kHz (e.g. resampled by the decoder) do not
+contain the frequencies removed by lossy encoders: they are never flagged.
+
+If the Song has no samples, DetectTranscode returns ErrNoSamples.
+*/
+func DetectTranscode(song *Song) (*Transcode, error) {
+	mono, err := song.monoSamples()
+	if err != nil {
+		return nil, err
+	}
+	power, resolution := meanSpectrum(mono, song.SampleRate)
+	spectrum := make([]float64, len(power))
+	for i, p := range power {
+		spectrum[i] = decibels(p)
+	}
+	spectrum = movingAverage(spectrum, 9)
+
+	bin := func(frequency float64) int {
+		k := int(frequency / resolution)
+		if k < 0 {
+			return 0
+		}
+		if k >= len(spectrum) {
+			return len(spectrum) - 1
+		}
+		return k
+	}
+	mean := func(low float64, high float64) float64 {
+		from, to := bin(low), bin(high)
+		var sum float64
+		for _, v := range spectrum[from : to+1] {
+			sum += v
+		}
+		return sum / float64(to-from+1)
+	}
+
+	reference := mean(transcodeReferenceLow, transcodeReferenceHigh)
+	cutoff := len(spectrum) - 1
+	for cutoff > 0 && spectrum[cutoff] < reference-cutoffDepth {
+		cutoff--
+	}
+	nyquist := float64(song.SampleRate) / 2
+	transcode := &Transcode{
+		Cutoff: float64(cutoff) * resolution,
+	}
+	if nyquist < minTranscodeNyquist || transcode.Cutoff > 0.95*nyquist {
+		return transcode, nil
+	}
+	transcode.Drop = mean(transcode.Cutoff-cliffWidth, transcode.Cutoff-cliffMargin) - mean(transcode.Cutoff+cliffMargin, transcode.Cutoff+cliffWidth)
+	transcode.Confidence = math.Max(0, math.Min(1, (transcode.Drop-cliffDrop/2)/cliffDrop))
+	transcode.Lossy = transcode.Drop >= cliffDrop
+	return transcode, nil
+}
@@ -0,0 +1,58 @@
+package bliss
+
+import (
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+// lowPassNoise returns n white noise samples (n a power of two) without the
+// frequencies above cutoff, or unfiltered if cutoff is zero.
+func lowPassNoise(sampleRate int, n int, cutoff float64) []float64 {
+	random := rand.New(rand.NewSource(1))
+	buffer := make([]complex128, n)
+	for i := range buffer {
+		buffer[i] = complex(random.Float64()-0.5, 0)
+	}
+	if cutoff > 0 {
+		fft(buffer)
+		for k := range buffer {
+			if f := float64(k) * float64(sampleRate) / float64(n); f > cutoff && f < float64(sampleRate)-cutoff {
+				buffer[k] = 0
+			}
+		}
+		// inverse DFT
+		for i := range buffer {
+			buffer[i] = cmplx.Conj(buffer[i])
+		}
+		fft(buffer)
+		for i := range buffer {
+			buffer[i] = cmplx.Conj(buffer[i]) / complex(float64(n), 0)
+		}
+	}
+	samples := make([]float64, n)
+	for i, v := range buffer {
+		samples[i] = real(v)
+	}
+	return samples
+}
+
+func TestDetectTranscode(t *testing.T) {
+	const rate = 44100
+	transcode, err := DetectTranscode(newTestSong(rate, 1, lowPassNoise(rate, 1<<17, 16000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !transcode.Lossy || transcode.Confidence < 0.9 {
+		t.Errorf("expected a low-passed song to be lossy, got: %+v", transcode)
+	}
+	assertNear(t, 16000, transcode.Cutoff, 200, "cutoff")
+
+	transcode, err = DetectTranscode(newTestSong(rate, 1, lowPassNoise(rate, 1<<17, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transcode.Lossy || transcode.Confidence > 0 {
+		t.Errorf("expected a full band song not to be lossy, got: %+v", transcode)
+	}
+}
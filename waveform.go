@@ -0,0 +1,186 @@
+package bliss
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+)
+
+// defaultSamplesPerPixel is the default zoom level of waveforms, as in audiowaveform.
+const defaultSamplesPerPixel = 256
+
+/*
+WaveformOptions stores the options of ComputeWaveform.
+*/
+type WaveformOptions struct {
+	/*
+		SamplesPerPixel is the number of samples (per channel) of each point of the
+		waveform. Defaults to 256.
+	*/
+	SamplesPerPixel int
+	/*
+		Bits is the resolution of the waveform points, 8 or 16. Defaults to 8.
+	*/
+	Bits int
+	/*
+		SplitChannels computes the waveform of each channel, rather than the waveform
+		of the channels mixed down to mono.
+	*/
+	SplitChannels bool
+}
+
+/*
+Waveform stores the min/max peaks of a Song, e.g. to draw its waveform overview.
+
+Waveform can be marshalled to (and unmarshalled from) the audiowaveform JSON format
+(version 2), so that it can be used by players supporting it.
+*/
+type Waveform struct {
+	/*
+		Version is the version of the audiowaveform format, 2.
+	*/
+	Version int `json:"version"`
+	/*
+		Channels is the number of channels of the waveform.
+	*/
+	Channels int `json:"channels"`
+	/*
+		SampleRate is the sampling rate of the song, in Hz.
+	*/
+	SampleRate int `json:"sample_rate"`
+	/*
+		SamplesPerPixel is the number of samples (per channel) of each point of the waveform.
+	*/
+	SamplesPerPixel int `json:"samples_per_pixel"`
+	/*
+		Bits is the resolution of the points, 8 (points from -128 to 127) or 16 (points
+		from -32768 to 32767).
+	*/
+	Bits int `json:"bits"`
+	/*
+		Length is the number of points of the waveform.
+	*/
+	Length int `json:"length"`
+	/*
+		Data stores, for each point, then for each channel, the minimum and maximum
+		sample values.
+	*/
+	Data []int `json:"data"`
+}
+
+/*
+ComputeWaveform computes the min/max peaks of a Song.
+
+Use Downsample on the result to get lower resolutions (e.g. for zoomed out views)
+without decoding the song again.
+
+If the Song has no samples, ComputeWaveform returns ErrNoSamples.
+*/
+func ComputeWaveform(song *Song, options WaveformOptions) (*Waveform, error) {
+	if options.SamplesPerPixel == 0 {
+		options.SamplesPerPixel = defaultSamplesPerPixel
+	}
+	if options.Bits == 0 {
+		options.Bits = 8
+	}
+	if options.SamplesPerPixel < 0 {
+		return nil, errors.New("bliss: invalid samples per pixel")
+	}
+	if options.Bits != 8 && options.Bits != 16 {
+		return nil, errors.New("bliss: waveform bits must be 8 or 16")
+	}
+	var channels [][]float64
+	if options.SplitChannels {
+		var err error
+		channels, err = song.channelSamples()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		mono, err := song.monoSamples()
+		if err != nil {
+			return nil, err
+		}
+		channels = [][]float64{mono}
+	}
+
+	scale := float64(int(1) << uint(options.Bits-1))
+	quantize := func(v float64) int {
+		return int(math.Max(-scale, math.Min(scale-1, math.Floor(v*scale))))
+	}
+	frames := len(channels[0])
+	length := (frames + options.SamplesPerPixel - 1) / options.SamplesPerPixel
+	waveform := &Waveform{
+		Version:         2,
+		Channels:        len(channels),
+		SampleRate:      song.SampleRate,
+		SamplesPerPixel: options.SamplesPerPixel,
+		Bits:            options.Bits,
+		Length:          length,
+		Data:            make([]int, 0, 2*length*len(channels)),
+	}
+	for start := 0; start < frames; start += options.SamplesPerPixel {
+		end := start + options.SamplesPerPixel
+		if end > frames {
+			end = frames
+		}
+		for _, channel := range channels {
+			min, max := channel[start], channel[start]
+			for _, v := range channel[start+1 : end] {
+				min = math.Min(min, v)
+				max = math.Max(max, v)
+			}
+			waveform.Data = append(waveform.Data, quantize(min), quantize(max))
+		}
+	}
+	return waveform, nil
+}
+
+/*
+Downsample returns the waveform at a lower resolution, merging factor points into one:
+the SamplesPerPixel of the returned waveform is factor times the SamplesPerPixel of waveform.
+*/
+func (waveform *Waveform) Downsample(factor int) (*Waveform, error) {
+	if factor <= 0 {
+		return nil, errors.New("bliss: invalid downsampling factor")
+	}
+	length := (waveform.Length + factor - 1) / factor
+	downsampled := *waveform
+	downsampled.SamplesPerPixel *= factor
+	downsampled.Length = length
+	downsampled.Data = make([]int, 0, 2*length*waveform.Channels)
+	for start := 0; start < waveform.Length; start += factor {
+		end := start + factor
+		if end > waveform.Length {
+			end = waveform.Length
+		}
+		for c := 0; c < waveform.Channels; c++ {
+			min, max := waveform.point(start, c)
+			for i := start + 1; i < end; i++ {
+				pointMin, pointMax := waveform.point(i, c)
+				if pointMin < min {
+					min = pointMin
+				}
+				if pointMax > max {
+					max = pointMax
+				}
+			}
+			downsampled.Data = append(downsampled.Data, min, max)
+		}
+	}
+	return &downsampled, nil
+}
+
+// point returns the minimum and maximum values of a channel at point i.
+func (waveform *Waveform) point(i int, channel int) (int, int) {
+	k := 2 * (i*waveform.Channels + channel)
+	return waveform.Data[k], waveform.Data[k+1]
+}
+
+/*
+WriteJSON writes the waveform in the audiowaveform JSON format to w.
+*/
+func (waveform *Waveform) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(waveform)
+}
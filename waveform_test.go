@@ -0,0 +1,76 @@
+package bliss
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestComputeWaveform(t *testing.T) {
+	const rate = 22050
+	samples := append(sine(rate, 1000, 441, 0.5), make([]float64, 1000)...)
+	waveform, err := ComputeWaveform(newTestSong(rate, 1, samples), WaveformOptions{
+		SamplesPerPixel: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, 20, waveform.Length, "length")
+	assertInt(t, 40, len(waveform.Data), "data length")
+	// a full period of the sine in each point
+	assertInt(t, -64, waveform.Data[0], "min")
+	assertInt(t, 63, waveform.Data[1], "max")
+	assertInt(t, 0, waveform.Data[38], "silent min")
+	assertInt(t, 0, waveform.Data[39], "silent max")
+
+	downsampled, err := waveform.Downsample(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, 300, downsampled.SamplesPerPixel, "downsampled samples per pixel")
+	assertInt(t, 7, downsampled.Length, "downsampled length")
+	assertInt(t, -64, downsampled.Data[0], "downsampled min")
+	assertInt(t, 63, downsampled.Data[1], "downsampled max")
+	// points 9 to 11 span the end of the sine
+	assertInt(t, -64, downsampled.Data[6], "downsampled boundary min")
+	assertInt(t, 0, downsampled.Data[8], "downsampled silent min")
+
+	var buffer bytes.Buffer
+	if err := waveform.WriteJSON(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buffer.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"version", "channels", "sample_rate", "samples_per_pixel", "bits", "length", "data"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("missing key %q in waveform JSON", key)
+		}
+	}
+}
+
+func TestComputeWaveformSplitChannels(t *testing.T) {
+	const rate = 22050
+	left := sine(rate, 1000, 441, 1)
+	right := make([]float64, 1000)
+	waveform, err := ComputeWaveform(newTestSong(rate, 2, interleave(left, right)), WaveformOptions{
+		SamplesPerPixel: 500,
+		Bits:            16,
+		SplitChannels:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, 2, waveform.Channels, "channels")
+	assertInt(t, 8, len(waveform.Data), "data length")
+	if waveform.Data[0] > -32000 || waveform.Data[1] < 32000 {
+		t.Errorf("expected full-scale left channel, got: %v", waveform.Data[:2])
+	}
+	assertInt(t, 0, waveform.Data[2], "right min")
+	assertInt(t, 0, waveform.Data[3], "right max")
+
+	if _, err := ComputeWaveform(newTestSong(rate, 2, interleave(left, right)), WaveformOptions{Bits: 12}); err == nil {
+		t.Error("expected an error for invalid bits")
+	}
+}
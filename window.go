@@ -0,0 +1,132 @@
+package bliss
+
+import (
+	"errors"
+	"time"
+)
+
+/*
+WindowAnalysis stores the analysis of a time range of a Song.
+*/
+type WindowAnalysis struct {
+	/*
+		Start is the start of the range, from the start of the song.
+	*/
+	Start time.Duration
+	/*
+		End is the end of the range, from the start of the song.
+	*/
+	End time.Duration
+	/*
+		Force is the overall force / strength of the range, like Song.Force.
+	*/
+	Force float32
+	/*
+		ForceRating is the overall force / strength category of the range, like Song.ForceRating.
+	*/
+	ForceRating ForceRating
+	/*
+		ForceVector stores the ratings of the range, like Song.ForceVector.
+	*/
+	ForceVector ForceVector
+}
+
+// forceOf returns the force of a force vector: bliss sums its amplitude and frequency ratings.
+func forceOf(forceVector ForceVector) float32 {
+	return forceVector.Amplitude + forceVector.Frequency
+}
+
+func forceRatingOf(force float32) ForceRating {
+	switch {
+	case force > 0:
+		return Loud
+	case force < 0:
+		return Calm
+	default:
+		return Unknown
+	}
+}
+
+// frames returns the number of samples per channel of the song.
+func (song *Song) frames() int {
+	if song.Channels <= 0 || song.BytesPerSample <= 0 {
+		return 0
+	}
+	return len(song.sampleBytes()) / (song.Channels * song.BytesPerSample)
+}
+
+// length returns the exact duration of the samples of the song.
+func (song *Song) length() time.Duration {
+	if song.SampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(song.frames()) / float64(song.SampleRate) * float64(time.Second))
+}
+
+func (song *Song) frameAt(t time.Duration) int {
+	frame := int(t.Seconds() * float64(song.SampleRate))
+	if frame < 0 {
+		return 0
+	}
+	if frames := song.frames(); frame > frames {
+		return frames
+	}
+	return frame
+}
+
+/*
+AnalyzeRange analyzes the samples of a Song between start and end, computing the
+same ratings as Analyze does for the whole song.
+
+start and end are clamped to the song duration.
+
+If the Song has no samples, or the range is empty, AnalyzeRange returns ErrNoSamples.
+*/
+func AnalyzeRange(song *Song, start time.Duration, end time.Duration) (*WindowAnalysis, error) {
+	from, to := song.frameAt(start), song.frameAt(end)
+	if song.SampleRate <= 0 || from >= to {
+		return nil, ErrNoSamples
+	}
+	frameBytes := song.Channels * song.BytesPerSample
+	forceVector := rateSamples(song, song.sampleBytes()[from*frameBytes:to*frameBytes])
+	force := forceOf(forceVector)
+	return &WindowAnalysis{
+		Start:       time.Duration(float64(from) / float64(song.SampleRate) * float64(time.Second)),
+		End:         time.Duration(float64(to) / float64(song.SampleRate) * float64(time.Second)),
+		Force:       force,
+		ForceRating: forceRatingOf(force),
+		ForceVector: forceVector,
+	}, nil
+}
+
+/*
+AnalyzeWindows analyzes sliding windows of a Song, returning the time series of
+their analyses.
+
+window is the duration of each window, and hop the duration between the start of
+two consecutive windows. Only full windows are analyzed, except if the song is
+shorter than window, in which case the whole song is analyzed as a single window.
+
+If the Song has no samples, AnalyzeWindows returns ErrNoSamples.
+*/
+func AnalyzeWindows(song *Song, window time.Duration, hop time.Duration) ([]WindowAnalysis, error) {
+	if window <= 0 || hop <= 0 {
+		return nil, errors.New("bliss: window and hop must be positive")
+	}
+	length := song.length()
+	if length == 0 {
+		return nil, ErrNoSamples
+	}
+	if length < window {
+		window = length
+	}
+	var windows []WindowAnalysis
+	for start := time.Duration(0); start+window <= length; start += hop {
+		analysis, err := AnalyzeRange(song, start, start+window)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, *analysis)
+	}
+	return windows, nil
+}
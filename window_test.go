@@ -0,0 +1,32 @@
+package bliss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnalyzeWindows(t *testing.T) {
+	const rate = 22050
+	song := newTestSong(rate, 2, sine(rate, 2*10*rate, 440, 0.5))
+	windows, err := AnalyzeWindows(song, 4*time.Second, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, 7, len(windows), "windows count")
+	for i, window := range windows {
+		if window.Start != time.Duration(i)*time.Second || window.End != window.Start+4*time.Second {
+			t.Errorf("window %d: unexpected range: %v-%v", i, window.Start, window.End)
+		}
+		assertFloat(t, forceOf(window.ForceVector), window.Force, "window force")
+	}
+
+	windows, err = AnalyzeWindows(song, time.Minute, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, 1, len(windows), "short song windows count")
+
+	if _, err := AnalyzeRange(song, 20*time.Second, 30*time.Second); err != ErrNoSamples {
+		t.Errorf("expected ErrNoSamples for a range after the song end, got: %v", err)
+	}
+}
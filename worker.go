@@ -0,0 +1,349 @@
+package bliss
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+const (
+	workerEnv            = "BLISS_WORKER"
+	workerMemoryLimitEnv = "BLISS_WORKER_MEMORY_LIMIT"
+)
+
+type workerRequest struct {
+	Filename string
+}
+
+type workerResponse struct {
+	Song  *Song  `json:",omitempty"`
+	Error string `json:",omitempty"`
+}
+
+/*
+ServeWorker turns the current process into a WorkerPool worker if it was started as one,
+and does nothing otherwise.
+
+Programs using WorkerPool must call ServeWorker at the very beginning of their main
+function, because workers are started by running the program executable again:
+
+	func main() {
+		bliss.ServeWorker()
+		// ...
+	}
+
+When the process is a worker, ServeWorker serves analysis requests until its pool
+closes it, then exits the process: it never returns.
+*/
+func ServeWorker() {
+	if os.Getenv(workerEnv) == "" {
+		return
+	}
+	if limit, err := strconv.ParseUint(os.Getenv(workerMemoryLimitEnv), 10, 64); err == nil && limit > 0 {
+		if err := setMemoryLimit(limit); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if err := serveWorker(os.Stdin, os.NewFile(3, "responses")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func serveWorker(requests io.Reader, responses io.Writer) error {
+	decoder := json.NewDecoder(requests)
+	encoder := json.NewEncoder(responses)
+	for {
+		var request workerRequest
+		if err := decoder.Decode(&request); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		var response workerResponse
+		song, err := Analyze(request.Filename)
+		if err != nil {
+			response.Error = err.Error()
+		} else {
+			response.Song = detachSong(song)
+			song.Close()
+		}
+		if err := encoder.Encode(&response); err != nil {
+			return err
+		}
+	}
+}
+
+/*
+WorkerOptions configures a WorkerPool.
+*/
+type WorkerOptions struct {
+	/*
+		Workers is the number of worker processes. Zero means runtime.NumCPU().
+	*/
+	Workers int
+	/*
+		Timeout limits the analysis of each file. A worker exceeding it is killed.
+		Zero means no timeout.
+	*/
+	Timeout time.Duration
+	/*
+		MemoryLimit limits the virtual memory of each worker process, in bytes.
+		A worker exceeding it fails its analysis and is restarted.
+		Zero means no limit. Only supported on Linux, macOS and FreeBSD.
+	*/
+	MemoryLimit uint64
+	/*
+		Executable is the path of the executable run for workers, which must call ServeWorker.
+		The empty string means the current executable.
+	*/
+	Executable string
+	/*
+		Args are the arguments passed to the worker executable.
+	*/
+	Args []string
+	/*
+		OnCrash, if non-nil, is called with the error of every analysis that killed
+		its worker, typically to quarantine the file.
+	*/
+	OnCrash func(err *WorkerCrashError)
+}
+
+/*
+WorkerCrashError is returned by WorkerPool when a worker process died or was killed
+while analyzing a file.
+*/
+type WorkerCrashError struct {
+	/*
+		Filename is the path of the file being analyzed when the worker died.
+	*/
+	Filename string
+	/*
+		Err is the cause of the crash: context.DeadlineExceeded if the worker was killed
+		after WorkerOptions.Timeout, or the worker process exit error otherwise.
+	*/
+	Err error
+}
+
+func (e *WorkerCrashError) Error() string {
+	if e.Err == context.DeadlineExceeded {
+		return fmt.Sprintf("bliss: worker timed out analyzing %s", e.Filename)
+	}
+	return fmt.Sprintf("bliss: worker crashed analyzing %s: %v", e.Filename, e.Err)
+}
+
+/*
+Timeout reports whether the worker was killed after WorkerOptions.Timeout.
+*/
+func (e *WorkerCrashError) Timeout() bool {
+	return e.Err == context.DeadlineExceeded
+}
+
+type worker struct {
+	cmd       *exec.Cmd
+	requests  io.WriteCloser
+	responses *os.File
+	encoder   *json.Encoder
+	decoder   *json.Decoder
+}
+
+func startWorker(options *WorkerOptions) (*worker, error) {
+	executable := options.Executable
+	if executable == "" {
+		var err error
+		if executable, err = os.Executable(); err != nil {
+			return nil, err
+		}
+	}
+	cmd := exec.Command(executable, options.Args...)
+	cmd.Env = append(os.Environ(), workerEnv+"=1", workerMemoryLimitEnv+"="+strconv.FormatUint(options.MemoryLimit, 10))
+	cmd.Stderr = os.Stderr
+	requests, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	responses, responsesWriter, err := os.Pipe()
+	if err != nil {
+		requests.Close()
+		return nil, err
+	}
+	cmd.ExtraFiles = []*os.File{responsesWriter}
+	err = cmd.Start()
+	responsesWriter.Close()
+	if err != nil {
+		requests.Close()
+		responses.Close()
+		return nil, err
+	}
+	return &worker{
+		cmd:       cmd,
+		requests:  requests,
+		responses: responses,
+		encoder:   json.NewEncoder(requests),
+		decoder:   json.NewDecoder(responses),
+	}, nil
+}
+
+func (w *worker) analyze(filename string) (*workerResponse, error) {
+	if err := w.encoder.Encode(&workerRequest{Filename: filename}); err != nil {
+		return nil, err
+	}
+	var response workerResponse
+	if err := w.decoder.Decode(&response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (w *worker) wait() error {
+	w.requests.Close()
+	err := w.cmd.Wait()
+	w.responses.Close()
+	return err
+}
+
+func (w *worker) kill() {
+	w.cmd.Process.Kill()
+	w.wait()
+}
+
+/*
+WorkerPool is an Analyzer that runs analyses in a pool of child processes, so that
+a file crashing the native bliss library does not take down the current process.
+
+Workers are started lazily by running the current executable again, which must call
+ServeWorker (see ServeWorker). Crashed or killed workers are restarted on the next
+analysis.
+
+Songs returned by a WorkerPool have nil Samples and do not own native memory.
+
+A WorkerPool must be closed with Close when done using it.
+*/
+type WorkerPool struct {
+	options WorkerOptions
+	slots   chan *worker
+}
+
+/*
+NewWorkerPool creates a WorkerPool configured by options.
+*/
+func NewWorkerPool(options WorkerOptions) (*WorkerPool, error) {
+	if options.MemoryLimit > 0 && !memoryLimitSupported {
+		return nil, errors.New("bliss: worker memory limits are not supported on " + runtime.GOOS)
+	}
+	if options.Workers <= 0 {
+		options.Workers = runtime.NumCPU()
+	}
+	pool := &WorkerPool{
+		options: options,
+		slots:   make(chan *worker, options.Workers),
+	}
+	for i := 0; i < options.Workers; i++ {
+		pool.slots <- nil
+	}
+	return pool, nil
+}
+
+/*
+Analyze analyzes filename in a worker process.
+
+If the worker dies or exceeds WorkerOptions.Timeout, Analyze returns a *WorkerCrashError.
+If ctx is done first, the worker is killed and Analyze returns ctx.Err().
+*/
+func (pool *WorkerPool) Analyze(ctx context.Context, filename string) (*Song, error) {
+	var w *worker
+	select {
+	case slot, ok := <-pool.slots:
+		if !ok {
+			return nil, errors.New("bliss: worker pool is closed")
+		}
+		w = slot
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if w == nil {
+		var err error
+		if w, err = startWorker(&pool.options); err != nil {
+			pool.slots <- nil
+			return nil, err
+		}
+	}
+
+	analyzeCtx := ctx
+	if pool.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		analyzeCtx, cancel = context.WithTimeout(ctx, pool.options.Timeout)
+		defer cancel()
+	}
+
+	type result struct {
+		response *workerResponse
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		response, err := w.analyze(filename)
+		done <- result{response, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			if err := w.wait(); err != nil {
+				r.err = err
+			}
+			pool.slots <- nil
+			return nil, pool.crash(filename, r.err)
+		}
+		pool.slots <- w
+		if r.response.Error != "" {
+			return nil, errors.New(r.response.Error)
+		}
+		return r.response.Song, nil
+	case <-analyzeCtx.Done():
+		w.kill()
+		<-done
+		pool.slots <- nil
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, pool.crash(filename, context.DeadlineExceeded)
+	}
+}
+
+func (pool *WorkerPool) crash(filename string, err error) error {
+	crash := &WorkerCrashError{
+		Filename: filename,
+		Err:      err,
+	}
+	if pool.options.OnCrash != nil {
+		pool.options.OnCrash(crash)
+	}
+	return crash
+}
+
+/*
+Close waits for running analyses to complete, then stops all workers.
+
+The WorkerPool must not be used after it is closed.
+*/
+func (pool *WorkerPool) Close() error {
+	var err error
+	for i := 0; i < pool.options.Workers; i++ {
+		if w := <-pool.slots; w != nil {
+			if e := w.wait(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	close(pool.slots)
+	return err
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package bliss
+
+import (
+	"errors"
+)
+
+const memoryLimitSupported = false
+
+func setMemoryLimit(limit uint64) error {
+	return errors.New("bliss: worker memory limits are not supported")
+}
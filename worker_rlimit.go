@@ -0,0 +1,17 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package bliss
+
+import (
+	"syscall"
+)
+
+const memoryLimitSupported = true
+
+func setMemoryLimit(limit uint64) error {
+	return syscall.Setrlimit(syscall.RLIMIT_AS, &syscall.Rlimit{
+		Cur: limit,
+		Max: limit,
+	})
+}
@@ -0,0 +1,57 @@
+package bliss
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	ServeWorker()
+	os.Exit(m.Run())
+}
+
+func TestWorkerPool(t *testing.T) {
+	pool, err := NewWorkerPool(WorkerOptions{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	_, err = pool.Analyze(context.Background(), "audio/missing.flac")
+	if err == nil {
+		t.Fatal("expected an error analyzing a missing file")
+	}
+	if _, ok := err.(*WorkerCrashError); ok {
+		t.Errorf("expected a decoding error, got: %v", err)
+	}
+	assertString(t, "bliss: couldn't decode song", err.Error(), "worker error")
+}
+
+func TestWorkerPoolCrash(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	var crashed string
+	pool, err := NewWorkerPool(WorkerOptions{
+		Workers:    1,
+		Executable: "/bin/sh",
+		Args:       []string{"-c", "exit 3"},
+		OnCrash: func(err *WorkerCrashError) {
+			crashed = err.Filename
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	for i := 0; i < 2; i++ {
+		_, err = pool.Analyze(context.Background(), "audio/song.flac")
+		if _, ok := err.(*WorkerCrashError); !ok {
+			t.Fatalf("expected a worker crash, got: %v", err)
+		}
+	}
+	assertString(t, "audio/song.flac", crashed, "crashed file")
+}